@@ -5,14 +5,15 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/6ixfigs/pingypongy/pkg/client"
 	"github.com/spf13/cobra"
 )
 
@@ -38,17 +39,25 @@ var leaderboard = &cobra.Command{
 }
 
 var leaderboardCreate = &cobra.Command{
-	Use:                   "create <name>",
-	Short:                 "Create a new leaderboard",
-	Long:                  "Creates a new leaderboard with the specified name.",
-	Aliases:               []string{"c"},
-	Example:               "pingo leaderboard create OnlyRealGs",
-	Args:                  cobra.ExactArgs(1),
-	DisableFlagsInUseLine: true,
+	Use:     "create <name>",
+	Short:   "Create a new leaderboard",
+	Long:    "Creates a new leaderboard with the specified name. Players on an elo leaderboard are ranked by rating, while players on a ladder move up by challenging and beating players above them.",
+	Aliases: []string{"c"},
+	Example: "pingo leaderboard create OnlyRealGs\npingo leaderboard create OnlyRealGs --type ladder --range 2 --expiry-days 5",
+	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := "/leaderboards"
-		formData := map[string]string{"name": args[0]}
-		return sendCommand(path, formData, http.MethodPost)
+		opts := &client.LeaderboardOptions{}
+		opts.Type, _ = cmd.Flags().GetString("type")
+
+		if cmd.Flags().Changed("range") {
+			opts.ChallengeRange, _ = cmd.Flags().GetInt("range")
+		}
+
+		if cmd.Flags().Changed("expiry-days") {
+			opts.ExpiryDays, _ = cmd.Flags().GetInt("expiry-days")
+		}
+
+		return show(cmd, pongo.CreateLeaderboard(args[0], opts))
 	},
 }
 
@@ -61,13 +70,12 @@ var leaderboardGet = &cobra.Command{
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s", args[0])
-		return sendCommand(path, nil, http.MethodGet)
+		return show(cmd, pongo.GetLeaderboard(args[0]))
 	},
 }
 
 var player = &cobra.Command{
-	Use:     "player {create,stats}",
+	Use:     "player {create,stats,link,unlink,accounts}",
 	Short:   "Create a player or retrieve stats",
 	Long:    "The player command enables you to create new players or retrieve their statistics. Players are the participants in your ping-pong matches, and their stats are tracked within leaderboards.",
 	Aliases: []string{"p"},
@@ -82,9 +90,7 @@ var playerCreate = &cobra.Command{
 	Args:                  cobra.ExactArgs(2),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s/players", args[0])
-		formData := map[string]string{"username": args[1]}
-		return sendCommand(path, formData, http.MethodPost)
+		return show(cmd, pongo.CreatePlayer(args[0], args[1]))
 	},
 }
 
@@ -97,30 +103,66 @@ var playerStats = &cobra.Command{
 	Args:                  cobra.ExactArgs(2),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s/players/%s", args[0], args[1])
-		return sendCommand(path, nil, http.MethodGet)
+		return show(cmd, pongo.GetPlayer(args[0], args[1]))
+	},
+}
+
+var playerLink = &cobra.Command{
+	Use:                   "link <leaderboard> <player> <provider> <id>",
+	Short:                 "Link a chat or email account to a player",
+	Long:                  "Links an external account to a player, so that chat commands know who \"me\" and @mentions are and notifications can mention the player. The provider is slack, discord or email, and the id is the Slack member ID, the Discord user ID or the email address. Linking another account with the same provider replaces the old one.",
+	Example:               "pingo player link OnlyRealGs 2pac slack U024BE7LH\npingo player link OnlyRealGs 2pac email 2pac@onlyrealgs.com",
+	Args:                  cobra.ExactArgs(4),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.LinkIdentity(args[0], args[1], args[2], args[3]))
+	},
+}
+
+var playerUnlink = &cobra.Command{
+	Use:                   "unlink <leaderboard> <player> <provider>",
+	Short:                 "Unlink an account from a player",
+	Long:                  "Unlinks the player's account with the given provider.",
+	Example:               "pingo player unlink OnlyRealGs 2pac slack",
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.UnlinkIdentity(args[0], args[1], args[2]))
+	},
+}
+
+var playerAccounts = &cobra.Command{
+	Use:                   "accounts <leaderboard> <player>",
+	Short:                 "List the accounts linked to a player",
+	Long:                  "Lists the chat and email accounts linked to a player.",
+	Example:               "pingo player accounts OnlyRealGs 2pac",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.ListIdentities(args[0], args[1]))
 	},
 }
 
 var webhooks = &cobra.Command{
-	Use:     "webhooks {register,list,delete}",
+	Use:     "webhooks {register,list,update,delete,test,deliveries,enable,rotate-secret,template}",
 	Short:   "Manage webhooks",
 	Long:    "The webhooks command allows you to manage webhooks for a leaderboard. Webhooks can be used to receive updates or notifications about match results and leaderboard changes.",
 	Aliases: []string{"w"},
 }
 
 var webhooksRegister = &cobra.Command{
-	Use:                   "register <leaderboard> <url>",
-	Short:                 "Register a webhook",
-	Long:                  "Registers a new webhook for a specified leaderboard. Use this command to set up a URL that will receive notifications about match results and other leaderboard events.",
-	Aliases:               []string{"r"},
-	Example:               "pingo webhooks register OnlyRealGs https://onlyrealgs.com/incoming",
-	Args:                  cobra.ExactArgs(2),
-	DisableFlagsInUseLine: true,
+	Use:     "register <leaderboard> <url>",
+	Short:   "Register a webhook",
+	Long:    "Registers a new webhook for a specified leaderboard. Use this command to set up a URL that will receive notifications about match results and other leaderboard events.",
+	Aliases: []string{"r"},
+	Example: "pingo webhooks register OnlyRealGs https://onlyrealgs.com/incoming\npingo webhooks register OnlyRealGs https://onlyrealgs.com/incoming --events match.recorded,player.created",
+	Args:    cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s/webhooks", args[0])
-		formData := map[string]string{"url": args[1]}
-		return sendCommand(path, formData, http.MethodPost)
+		opts := &client.WebhookOptions{}
+		opts.Events, _ = cmd.Flags().GetStringSlice("events")
+		opts.Format, _ = cmd.Flags().GetString("format")
+
+		return show(cmd, pongo.RegisterWebhook(args[0], args[1], opts))
 	},
 }
 
@@ -133,22 +175,68 @@ var webhooksList = &cobra.Command{
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s/webhooks", args[0])
-		return sendCommand(path, nil, http.MethodGet)
+		return show(cmd, pongo.ListWebhooks(args[0]))
+	},
+}
+
+var webhooksUpdate = &cobra.Command{
+	Use:     "update <leaderboard> <webhook-id>",
+	Short:   "Update a webhook",
+	Long:    "Updates the URL, format, events or status of a webhook. Only the given flags are changed, except that a new --url without --format picks the format from the URL. Pass an empty --events to subscribe to all events again.",
+	Aliases: []string{"u"},
+	Example: "pingo webhooks update OnlyRealGs 3 --url https://onlyrealgs.com/new\npingo webhooks update OnlyRealGs 3 --events match.recorded --enabled=false",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhookID, err := parseID("webhook", args[1])
+		if err != nil {
+			return err
+		}
+
+		update := &client.WebhookUpdate{}
+
+		if cmd.Flags().Changed("url") {
+			webhookURL, _ := cmd.Flags().GetString("url")
+			update.URL = &webhookURL
+		}
+
+		if cmd.Flags().Changed("format") {
+			format, _ := cmd.Flags().GetString("format")
+			update.Format = &format
+		}
+
+		if cmd.Flags().Changed("events") {
+			events, _ := cmd.Flags().GetStringSlice("events")
+			update.Events = append([]string{}, events...)
+		}
+
+		if cmd.Flags().Changed("enabled") {
+			enabled, _ := cmd.Flags().GetBool("enabled")
+			update.Enabled = &enabled
+		}
+
+		return show(cmd, pongo.UpdateWebhook(args[0], webhookID, update))
 	},
 }
 
 var webhooksDelete = &cobra.Command{
-	Use:                   "delete <leaderboard>",
-	Short:                 "Delete all webhooks",
-	Long:                  "Deletes all registered webhooks for a specified leaderboard. Use this command to remove webhooks and stop receiving notifications for a leaderboard.",
+	Use:                   "delete <leaderboard> [webhook-id]",
+	Short:                 "Delete a webhook, or all of them",
+	Long:                  "Deletes the webhook with the given id, or all registered webhooks for a specified leaderboard if no id is given. Use this command to remove webhooks and stop receiving notifications for a leaderboard.",
 	Aliases:               []string{"d"},
-	Example:               "pingo webhooks delete OnlyRealGs",
-	Args:                  cobra.ExactArgs(1),
+	Example:               "pingo webhooks delete OnlyRealGs 3\npingo webhooks delete OnlyRealGs",
+	Args:                  cobra.RangeArgs(1, 2),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		leaderboard := args[0]
 
+		if len(args) == 2 {
+			webhookID, err := parseID("webhook", args[1])
+			if err != nil {
+				return err
+			}
+			return show(cmd, pongo.DeleteWebhook(leaderboard, webhookID))
+		}
+
 		fmt.Printf("\n> Are you sure you want to delete all webhooks from '%s' (y/n)? ", leaderboard)
 		reader := bufio.NewReader(os.Stdin)
 		input, err := reader.ReadString('\n')
@@ -158,8 +246,7 @@ var webhooksDelete = &cobra.Command{
 		input = strings.TrimSpace(strings.ToLower(input))
 
 		if input == "y" || input == "yes" {
-			path := fmt.Sprintf("/leaderboards/%s/webhooks", args[0])
-			return sendCommand(path, nil, http.MethodDelete)
+			return show(cmd, pongo.DeleteWebhooks(leaderboard))
 		} else {
 			fmt.Println("Delete operation cancelled.")
 			return nil
@@ -167,18 +254,314 @@ var webhooksDelete = &cobra.Command{
 	},
 }
 
+var webhooksTest = &cobra.Command{
+	Use:                   "test <leaderboard> <webhook-id>",
+	Short:                 "Send a test notification to a webhook",
+	Long:                  "Sends a sample match result to a webhook straight away and shows how the receiver responded. Use this command to check that a webhook works without recording a match.",
+	Aliases:               []string{"t"},
+	Example:               "pingo webhooks test OnlyRealGs 3",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhookID, err := parseID("webhook", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.TestWebhook(args[0], webhookID))
+	},
+}
+
+var webhooksDeliveries = &cobra.Command{
+	Use:                   "deliveries <leaderboard> <webhook-id>",
+	Short:                 "Show the delivery log of a webhook",
+	Long:                  "Shows the most recent delivery attempts of a webhook, along with the HTTP status, latency and error of each. Use this command to find out why a webhook isn't receiving notifications.",
+	Example:               "pingo webhooks deliveries OnlyRealGs 3",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhookID, err := parseID("webhook", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.WebhookDeliveries(args[0], webhookID))
+	},
+}
+
+var webhooksEnable = &cobra.Command{
+	Use:                   "enable <leaderboard> <webhook-id>",
+	Short:                 "Re-enable a disabled webhook",
+	Long:                  "Re-enables a webhook that was disabled after its deliveries kept failing. Use this command once the receiving end has been fixed.",
+	Aliases:               []string{"e"},
+	Example:               "pingo webhooks enable OnlyRealGs 3",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhookID, err := parseID("webhook", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.EnableWebhook(args[0], webhookID))
+	},
+}
+
+var webhooksRotateSecret = &cobra.Command{
+	Use:                   "rotate-secret <leaderboard> <webhook-id>",
+	Short:                 "Rotate the signing secret of a webhook",
+	Long:                  "Replaces the secret a webhook's notifications are signed with and prints the new one. The old secret stops working straight away, so update the receiver right after.",
+	Example:               "pingo webhooks rotate-secret OnlyRealGs 3",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		webhookID, err := parseID("webhook", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.RotateWebhookSecret(args[0], webhookID))
+	},
+}
+
+var webhooksTemplate = &cobra.Command{
+	Use:   "template {set,list,delete,preview}",
+	Short: "Manage message templates",
+	Long:  "The template command allows you to change the wording of notifications. Templates are Go text/template templates set per event type, either on a leaderboard or on a single webhook, and have access to all of the event's data.",
+}
+
+var webhooksTemplateSet = &cobra.Command{
+	Use:     "set <leaderboard> <event> <template>",
+	Short:   "Set the template for an event",
+	Long:    "Sets the template for an event type on a leaderboard, or on a single webhook with --webhook. A webhook's templates take precedence over the leaderboard's. The template is checked against sample data before it is saved.",
+	Example: "pingo webhooks template set OnlyRealGs match.recorded '{{.Match.Winner}} won {{.Match.Score}}!'\npingo webhooks template set OnlyRealGs match.recorded '{{.Match.Score}}' --webhook 3",
+	Args:    cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("webhook") {
+			webhookID, _ := cmd.Flags().GetInt("webhook")
+			return show(cmd, pongo.SetWebhookTemplate(args[0], webhookID, args[1], args[2]))
+		}
+		return show(cmd, pongo.SetTemplate(args[0], args[1], args[2]))
+	},
+}
+
+var webhooksTemplateList = &cobra.Command{
+	Use:                   "list <leaderboard>",
+	Short:                 "List message templates",
+	Long:                  "Lists the templates set on a leaderboard and on its webhooks.",
+	Example:               "pingo webhooks template list OnlyRealGs",
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.ListTemplates(args[0]))
+	},
+}
+
+var webhooksTemplateDelete = &cobra.Command{
+	Use:     "delete <leaderboard> <event>",
+	Short:   "Delete the template for an event",
+	Long:    "Deletes the template for an event type from a leaderboard, or from a single webhook with --webhook, so that notifications use the default wording again.",
+	Example: "pingo webhooks template delete OnlyRealGs match.recorded\npingo webhooks template delete OnlyRealGs match.recorded --webhook 3",
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("webhook") {
+			webhookID, _ := cmd.Flags().GetInt("webhook")
+			return show(cmd, pongo.DeleteWebhookTemplate(args[0], webhookID, args[1]))
+		}
+		return show(cmd, pongo.DeleteTemplate(args[0], args[1]))
+	},
+}
+
+var webhooksTemplatePreview = &cobra.Command{
+	Use:                   "preview <leaderboard> <event> <template>",
+	Short:                 "Preview a template",
+	Long:                  "Renders a template against sample data for an event type without saving it. Use this command to try out a template before setting it.",
+	Example:               "pingo webhooks template preview OnlyRealGs match.recorded '{{.Match.Winner}} won {{.Match.Score}}!'",
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.PreviewTemplate(args[0], args[1], args[2]))
+	},
+}
+
 var record = &cobra.Command{
-	Use:                   "record <leaderboard> <player1> <player2> <score>",
-	Short:                 "Record a match between two players",
-	Long:                  "Records the outcome of a match between two players in a specified leaderboard. Use this command to log match results, update player rankings, and maintain an accurate recordof played matches.",
+	Use:     "record <leaderboard> <player1> <player2> <score>",
+	Short:   "Record a match between two players",
+	Long:    "Records the outcome of a match between two players in a specified leaderboard. Use this command to log match results, update player rankings, and maintain an accurate recordof played matches.",
+	Aliases: []string{"r"},
+	Example: "pingo record OnlyRealGs eazy-e 2pac 2-1\npingo record OnlyRealGs eazy-e 2pac 2-1 --by eazy-e",
+	Args:    cobra.ExactArgs(4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordedBy, _ := cmd.Flags().GetString("by")
+		return show(cmd, pongo.RecordMatch(args[0], args[1], args[2], args[3], recordedBy))
+	},
+}
+
+var predict = &cobra.Command{
+	Use:                   "predict <leaderboard> <player1> <player2>",
+	Short:                 "Predict the outcome of a match",
+	Long:                  "Predicts the outcome of a match between two players in a specified leaderboard without recording it. Use this command to see each player's expected score, win probability and how much Elo they would gain or lose for a win, draw or loss.",
+	Example:               "pingo predict OnlyRealGs eazy-e 2pac",
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.Predict(args[0], args[1], args[2]))
+	},
+}
+
+var suggest = &cobra.Command{
+	Use:                   "suggest <leaderboard> <player>",
+	Short:                 "Suggest opponents for a player",
+	Long:                  "Suggests who a player should play next on a specified leaderboard. Opponents are ranked by how close the match would be, how long it has been since the two last played and whether they have ever met.",
+	Example:               "pingo suggest OnlyRealGs 2pac",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.SuggestOpponents(args[0], args[1]))
+	},
+}
+
+var tournament = &cobra.Command{
+	Use:     "tournament {create,list,show,record}",
+	Short:   "Run tournaments",
+	Long:    "The tournament command allows you to run single-elimination, round-robin and Swiss tournaments on a leaderboard. Players are seeded by their current Elo and tournament matches are recorded like any other match, so ratings still update.",
+	Aliases: []string{"t"},
+}
+
+var tournamentCreate = &cobra.Command{
+	Use:     "create <leaderboard> <name> <player>...",
+	Short:   "Create a tournament",
+	Long:    "Creates a new tournament on the specified leaderboard. Players are seeded by their current Elo. A single-elimination bracket gives the top seeds a bye when the number of players isn't a power of two, a round-robin schedules every player against every other player and a Swiss tournament pairs players with the same score each round.",
+	Aliases: []string{"c"},
+	Example: "pingo tournament create OnlyRealGs WestCoastCup 2pac eazy-e snoop dre\npingo tournament create OnlyRealGs League --format round-robin 2pac eazy-e snoop dre",
+	Args:    cobra.MinimumNArgs(4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := &client.TournamentOptions{}
+		opts.Format, _ = cmd.Flags().GetString("format")
+
+		if cmd.Flags().Changed("rounds") {
+			opts.Rounds, _ = cmd.Flags().GetInt("rounds")
+		}
+
+		return show(cmd, pongo.CreateTournament(args[0], args[1], args[2:], opts))
+	},
+}
+
+var tournamentList = &cobra.Command{
+	Use:                   "list <leaderboard>",
+	Short:                 "List all tournaments",
+	Long:                  "Lists all tournaments on the specified leaderboard along with their winners.",
+	Aliases:               []string{"l"},
+	Example:               "pingo tournament list OnlyRealGs",
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.ListTournaments(args[0]))
+	},
+}
+
+var tournamentShow = &cobra.Command{
+	Use:                   "show <leaderboard> <name>",
+	Short:                 "Show a tournament bracket",
+	Long:                  "Shows the bracket of the specified tournament with the results of every match played so far.",
+	Aliases:               []string{"s"},
+	Example:               "pingo tournament show OnlyRealGs WestCoastCup",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.GetTournament(args[0], args[1]))
+	},
+}
+
+var tournamentRecord = &cobra.Command{
+	Use:                   "record <leaderboard> <name> <player1> <player2> <score>",
+	Short:                 "Record a tournament match",
+	Long:                  "Records the outcome of a tournament match. The match is recorded on the leaderboard as usual and the winner moves on to the next round.",
 	Aliases:               []string{"r"},
-	Example:               "pingo record OnlyRealGs eazy-e 2pac 2-1",
-	Args:                  cobra.ExactArgs(4),
+	Example:               "pingo tournament record OnlyRealGs WestCoastCup 2pac dre 2-1",
+	Args:                  cobra.ExactArgs(5),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.RecordTournamentMatch(args[0], args[1], args[2], args[3], args[4]))
+	},
+}
+
+var ladder = &cobra.Command{
+	Use:     "ladder {challenge,challenges,accept,play,history}",
+	Short:   "Manage ladder challenges",
+	Long:    "The ladder command allows you to challenge players on a ladder leaderboard. A player can challenge anyone a few places above them and takes their place by beating them. Challenges that aren't played in time are forfeited by the challenged player.",
+	Aliases: []string{"ld"},
+}
+
+var ladderChallenge = &cobra.Command{
+	Use:                   "challenge <leaderboard> <challenger> <challenged>",
+	Short:                 "Challenge a player",
+	Long:                  "Challenges a player higher up on the specified ladder.",
+	Aliases:               []string{"c"},
+	Example:               "pingo ladder challenge OnlyRealGs eazy-e 2pac",
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.Challenge(args[0], args[1], args[2]))
+	},
+}
+
+var ladderChallenges = &cobra.Command{
+	Use:                   "challenges <leaderboard>",
+	Short:                 "List open challenges",
+	Long:                  "Lists the challenges on the specified ladder that haven't been played yet.",
+	Aliases:               []string{"l"},
+	Example:               "pingo ladder challenges OnlyRealGs",
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return show(cmd, pongo.ListChallenges(args[0]))
+	},
+}
+
+var ladderAccept = &cobra.Command{
+	Use:                   "accept <leaderboard> <challenge>",
+	Short:                 "Accept a challenge",
+	Long:                  "Accepts a challenge on the specified ladder. The challenge still has to be played before it expires.",
+	Aliases:               []string{"a"},
+	Example:               "pingo ladder accept OnlyRealGs 7",
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		challengeID, err := parseID("challenge", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.AcceptChallenge(args[0], challengeID))
+	},
+}
+
+var ladderPlay = &cobra.Command{
+	Use:                   "play <leaderboard> <challenge> <score>",
+	Short:                 "Record the result of a challenge",
+	Long:                  "Records the result of an accepted challenge, with the challenger's score first. The match is recorded on the leaderboard as usual and the players swap places if the challenger wins.",
+	Aliases:               []string{"p"},
+	Example:               "pingo ladder play OnlyRealGs 7 2-1",
+	Args:                  cobra.ExactArgs(3),
 	DisableFlagsInUseLine: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := fmt.Sprintf("/leaderboards/%s/matches", args[0])
-		formData := map[string]string{"player1": args[1], "player2": args[2], "score": args[3]}
-		return sendCommand(path, formData, http.MethodPost)
+		challengeID, err := parseID("challenge", args[1])
+		if err != nil {
+			return err
+		}
+		return show(cmd, pongo.PlayChallenge(args[0], challengeID, args[2]))
+	},
+}
+
+var ladderHistory = &cobra.Command{
+	Use:     "history <leaderboard> [player]",
+	Short:   "Show position history",
+	Long:    "Shows how positions on the specified ladder have changed, optionally for a single player.",
+	Aliases: []string{"h"},
+	Example: "pingo ladder history OnlyRealGs\npingo ladder history OnlyRealGs 2pac",
+	Args:    cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		player := ""
+		if len(args) == 2 {
+			player = args[1]
+		}
+		return show(cmd, pongo.LadderHistory(args[0], player))
 	},
 }
 
@@ -187,55 +570,93 @@ func init() {
 
 	pingo.AddCommand(version)
 
+	leaderboardCreate.Flags().String("type", "elo", "leaderboard type: elo or ladder")
+	leaderboardCreate.Flags().Int("range", 3, "how many places above them a player can challenge on a ladder")
+	leaderboardCreate.Flags().Int("expiry-days", 7, "days to play a ladder challenge before it is forfeited")
 	leaderboard.AddCommand(leaderboardCreate)
 	leaderboard.AddCommand(leaderboardGet)
 	pingo.AddCommand(leaderboard)
 
 	player.AddCommand(playerCreate)
 	player.AddCommand(playerStats)
+	player.AddCommand(playerLink)
+	player.AddCommand(playerUnlink)
+	player.AddCommand(playerAccounts)
 	pingo.AddCommand(player)
 
+	webhooksRegister.Flags().StringSlice("events", nil, "events to subscribe to, e.g. match.recorded,player.created (default all events)")
+	webhooksRegister.Flags().String("format", "", "payload format: slack, text, discord, teams or json (default picked from the URL)")
 	webhooks.AddCommand(webhooksRegister)
 	webhooks.AddCommand(webhooksList)
+	webhooksUpdate.Flags().String("url", "", "new URL of the webhook")
+	webhooksUpdate.Flags().String("format", "", "payload format: slack, text, discord, teams or json")
+	webhooksUpdate.Flags().StringSlice("events", nil, "events to subscribe to, e.g. match.recorded,player.created")
+	webhooksUpdate.Flags().Bool("enabled", true, "whether the webhook is notified")
+	webhooks.AddCommand(webhooksUpdate)
 	webhooks.AddCommand(webhooksDelete)
+	webhooks.AddCommand(webhooksTest)
+	webhooks.AddCommand(webhooksDeliveries)
+	webhooks.AddCommand(webhooksEnable)
+	webhooks.AddCommand(webhooksRotateSecret)
+	webhooksTemplateSet.Flags().Int("webhook", 0, "id of the webhook to set the template on (default the whole leaderboard)")
+	webhooksTemplate.AddCommand(webhooksTemplateSet)
+	webhooksTemplate.AddCommand(webhooksTemplateList)
+	webhooksTemplateDelete.Flags().Int("webhook", 0, "id of the webhook to delete the template from (default the whole leaderboard)")
+	webhooksTemplate.AddCommand(webhooksTemplateDelete)
+	webhooksTemplate.AddCommand(webhooksTemplatePreview)
+	webhooks.AddCommand(webhooksTemplate)
 	pingo.AddCommand(webhooks)
 
+	tournamentCreate.Flags().String("format", "single-elimination", "tournament format: single-elimination, round-robin or swiss")
+	tournamentCreate.Flags().Int("rounds", 0, "number of rounds of a Swiss tournament (default enough to find a single winner)")
+	tournament.AddCommand(tournamentCreate)
+	tournament.AddCommand(tournamentList)
+	tournament.AddCommand(tournamentShow)
+	tournament.AddCommand(tournamentRecord)
+	pingo.AddCommand(tournament)
+
+	ladder.AddCommand(ladderChallenge)
+	ladder.AddCommand(ladderChallenges)
+	ladder.AddCommand(ladderAccept)
+	ladder.AddCommand(ladderPlay)
+	ladder.AddCommand(ladderHistory)
+	pingo.AddCommand(ladder)
+
+	record.Flags().String("by", "", "player reporting the result, who then can't confirm or dispute it")
 	pingo.AddCommand(record)
+	pingo.AddCommand(predict)
+	pingo.AddCommand(suggest)
 }
 
-func sendCommand(path string, formData map[string]string, method string) error {
-
-	form := url.Values{}
-	for key, value := range formData {
-		form.Set(key, value)
-	}
+// pongo is the client of the pongo server. Its URL is asked for the first
+// time a command calls it.
+var pongo = &client.Client{}
 
-	serverURL, err := getServerURL()
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(method, serverURL+path, strings.NewReader(form.Encode()))
-	if err != nil {
-		return err
+// show makes the call and prints the text the server responded with.
+// Errors come with their code, and are printed by cobra.
+func show[T any](cmd *cobra.Command, call *client.Call[T]) error {
+	if pongo.BaseURL == "" {
+		serverURL, err := getServerURL()
+		if err != nil {
+			return err
+		}
+		pongo.BaseURL = strings.TrimSuffix(serverURL, "/")
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	status, text, err := call.Text(cmd.Context())
+	if text != "" {
+		fmt.Printf("%s\n%s", status, text)
 	}
-	defer resp.Body.Close()
+	return err
+}
 
-	text, err := io.ReadAll(resp.Body)
+// parseID parses the id of a webhook or challenge given as an argument.
+func parseID(name, arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("invalid %s id: %s", name, arg)
 	}
-
-	fmt.Printf("%s\n%s", resp.Status, string(text))
-	return nil
+	return id, nil
 }
 
 func getServerURL() (string, error) {
@@ -272,7 +693,10 @@ func getServerURL() (string, error) {
 }
 
 func main() {
-	err := pingo.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := pingo.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
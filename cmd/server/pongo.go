@@ -1,14 +1,62 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/6ixfigs/pingypongy/internal/discord"
 	"github.com/6ixfigs/pingypongy/internal/rest"
+	"github.com/spf13/cobra"
 )
 
-func main() {
+var pongo = &cobra.Command{
+	Use:          "pongo",
+	Short:        "Pongo API server",
+	Long:         "Runs the Pongo API server. Its subcommands set up the integrations with chats.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		serve()
+	},
+}
+
+var discordCmd = &cobra.Command{
+	Use:   "discord {register-commands}",
+	Short: "Manage the Discord integration",
+}
+
+var discordRegisterCommands = &cobra.Command{
+	Use:     "register-commands",
+	Short:   "Register the /pong command with Discord",
+	Long:    "Registers the /pong command with the Discord application in DISCORD_APPLICATION_ID, replacing the commands registered before. Global commands can take up to an hour to show up; use --guild to register them on a single server straight away while testing.",
+	Example: "pongo discord register-commands\npongo discord register-commands --guild 613425648685547541",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Get()
+		if err != nil {
+			return err
+		}
+
+		guildID, _ := cmd.Flags().GetString("guild")
+		if err := discord.RegisterCommands(cfg, guildID); err != nil {
+			return err
+		}
+
+		fmt.Println("Registered the /pong command.")
+		return nil
+	},
+}
+
+func init() {
+	discordRegisterCommands.Flags().String("guild", "", "id of the server to register the command on (default all servers)")
+	discordCmd.AddCommand(discordRegisterCommands)
+	pongo.AddCommand(discordCmd)
+}
+
+func serve() {
 	logfile, err := os.OpenFile("/var/log/pongo/pongo.log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		log.Fatalf("error opening log file: %v", err)
@@ -25,8 +73,23 @@ func main() {
 
 	s.MountRoutes()
 
+	if err := s.ScheduleDailyPairings(); err != nil {
+		log.Fatal("failed to schedule daily pairings: ", err)
+	}
+
+	s.ScheduleLadderSweep()
+
+	s.StartDispatcher()
+
 	log.Printf("server running on port %s", s.Cfg.ServerPort)
 	if err := http.ListenAndServe(":"+s.Cfg.ServerPort, s.Rtr); err != nil {
 		log.Fatal("server failed to start: ", err)
 	}
 }
+
+func main() {
+	err := pongo.Execute()
+	if err != nil {
+		os.Exit(1)
+	}
+}
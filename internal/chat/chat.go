@@ -0,0 +1,92 @@
+// Package chat runs the commands chat integrations offer, such as Slack's
+// /pong, against the REST API, so that they behave exactly like the API
+// and the CLI do.
+package chat
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// Usage lists the commands.
+const Usage = "Usage:\n" +
+	"• `record <player1> <player2> <score>` records a match, e.g. `record alice bob 2-1`\n" +
+	"• `board` shows the leaderboard\n" +
+	"• `stats <player>` shows a player's stats\n" +
+	"• `confirm <match-id>` and `dispute <match-id>` confirm or dispute a match you played\n"
+
+// Reply is the outcome of a command. Public replies are shown to everyone in
+// the channel, the rest only to whoever ran the command.
+type Reply struct {
+	Text   string
+	Public bool
+}
+
+// Run runs the command in args on the leaderboard by sending the matching
+// request to api, which should be the REST API's router. player is who ran
+// the command: the player their account is linked to, or empty if it isn't.
+// Matches are recorded by them, and confirmed or disputed on their behalf.
+func Run(api http.Handler, leaderboard, player string, args []string) *Reply {
+	if len(args) == 0 {
+		return &Reply{Text: Usage}
+	}
+
+	base := "/api/v1/leaderboards/" + url.PathEscape(leaderboard)
+	switch args[0] {
+	case "record":
+		if len(args) != 4 {
+			return &Reply{Text: "Usage: `record <player1> <player2> <score>`\n"}
+		}
+		form := url.Values{
+			"player1": {args[1]},
+			"player2": {args[2]},
+			"score":   {args[3]},
+		}
+		if player != "" {
+			form.Set("recorded_by", player)
+		}
+		return call(api, http.MethodPost, base+"/matches", form, true)
+	case "board", "leaderboard":
+		return call(api, http.MethodGet, base, nil, false)
+	case "stats":
+		if len(args) != 2 {
+			return &Reply{Text: "Usage: `stats <player>`\n"}
+		}
+		return call(api, http.MethodGet, base+"/players/"+url.PathEscape(args[1]), nil, false)
+	case "confirm", "dispute":
+		if len(args) != 2 {
+			return &Reply{Text: fmt.Sprintf("Usage: `%s <match-id>`\n", args[0])}
+		}
+		if player == "" {
+			return &Reply{Text: fmt.Sprintf("Link your account to your player to %s matches.\n", args[0])}
+		}
+		form := url.Values{"player": {player}}
+		return call(api, http.MethodPost, base+"/matches/"+url.PathEscape(args[1])+"/"+args[0], form, true)
+	case "help":
+		return &Reply{Text: Usage}
+	default:
+		return &Reply{Text: fmt.Sprintf("Unknown command %s.\n%s", args[0], Usage)}
+	}
+}
+
+// call sends a request to the API and turns its response into a reply. Only
+// successful requests are public, so that typos don't clutter the channel.
+func call(api http.Handler, method, path string, form url.Values, public bool) *Reply {
+	r, err := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return &Reply{Text: "Something went wrong."}
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+
+	ok := w.Code >= 200 && w.Code < 300
+	return &Reply{
+		Text:   w.Body.String(),
+		Public: public && ok,
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/joho/godotenv"
@@ -12,6 +13,34 @@ type Config struct {
 	ServerPort string
 	DBConn     string
 	BotToken   string
+
+	// DailyPairingsAt is the time of day, as HH:MM, at which suggested
+	// matches are posted to webhooks. Daily pairings are off when empty.
+	DailyPairingsAt string
+
+	// WebhookAllowlist lists the hosts, IP addresses and CIDR ranges that
+	// webhooks may reach even though they are private, such as an internal
+	// chat server.
+	WebhookAllowlist []string
+
+	// SlackSigningSecret verifies that requests to the Slack endpoints come
+	// from Slack. The endpoints are off when empty.
+	SlackSigningSecret string
+
+	// SlackLeaderboard is the leaderboard Slack commands are run on.
+	SlackLeaderboard string
+
+	// DiscordPublicKey verifies that requests to the Discord endpoint come
+	// from Discord. The endpoint is off when empty.
+	DiscordPublicKey string
+
+	// DiscordApplicationID and DiscordBotToken are used to register
+	// commands and to look up users.
+	DiscordApplicationID string
+	DiscordBotToken      string
+
+	// DiscordLeaderboard is the leaderboard Discord commands are run on.
+	DiscordLeaderboard string
 }
 
 var (
@@ -36,7 +65,17 @@ func Get() (*Config, error) {
 				os.Getenv("DB_PORT"),
 				os.Getenv("DB_NAME"),
 			),
-			BotToken: os.Getenv("BOT_TOKEN"),
+			BotToken:         os.Getenv("BOT_TOKEN"),
+			DailyPairingsAt:  os.Getenv("DAILY_PAIRINGS_AT"),
+			WebhookAllowlist: strings.Split(os.Getenv("WEBHOOK_ALLOWLIST"), ","),
+
+			SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+			SlackLeaderboard:   os.Getenv("SLACK_LEADERBOARD"),
+
+			DiscordPublicKey:     os.Getenv("DISCORD_PUBLIC_KEY"),
+			DiscordApplicationID: os.Getenv("DISCORD_APPLICATION_ID"),
+			DiscordBotToken:      os.Getenv("DISCORD_BOT_TOKEN"),
+			DiscordLeaderboard:   os.Getenv("DISCORD_LEADERBOARD"),
 		}
 	})
 
@@ -0,0 +1,115 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/config"
+)
+
+// apiURL is the base URL of the Discord API.
+var apiURL = "https://discord.com/api/v10"
+
+// Application command and option types.
+const (
+	chatInputCommand = 1
+	subcommandOption = 1
+	stringOption     = 3
+)
+
+type command struct {
+	Name        string    `json:"name"`
+	Type        int       `json:"type,omitempty"`
+	Description string    `json:"description"`
+	Options     []*option `json:"options,omitempty"`
+}
+
+type option struct {
+	Type        int       `json:"type"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Required    bool      `json:"required,omitempty"`
+	Options     []*option `json:"options,omitempty"`
+}
+
+// commands are the application commands pongo handles. Players can be given
+// as usernames, as me, or by mentioning them.
+var commands = []*command{
+	{
+		Name:        "pong",
+		Type:        chatInputCommand,
+		Description: "Ping pong scores, stats and leaderboards",
+		Options: []*option{
+			{
+				Type:        subcommandOption,
+				Name:        "record",
+				Description: "Record a match between two players",
+				Options: []*option{
+					{Type: stringOption, Name: "player1", Description: "First player", Required: true},
+					{Type: stringOption, Name: "player2", Description: "Second player", Required: true},
+					{Type: stringOption, Name: "score", Description: "Score, first player's first, e.g. 2-1", Required: true},
+				},
+			},
+			{
+				Type:        subcommandOption,
+				Name:        "leaderboard",
+				Description: "Show the leaderboard",
+			},
+			{
+				Type:        subcommandOption,
+				Name:        "stats",
+				Description: "Show a player's stats",
+				Options: []*option{
+					{Type: stringOption, Name: "player", Description: "Player, yourself if left out"},
+				},
+			},
+		},
+	},
+}
+
+// RegisterCommands registers pongo's commands with Discord, replacing the
+// ones registered before. Commands registered on a guild are available
+// straight away, while global ones can take up to an hour to show up.
+func RegisterCommands(cfg *config.Config, guildID string) error {
+	if cfg.DiscordApplicationID == "" || cfg.DiscordBotToken == "" {
+		return fmt.Errorf("DISCORD_APPLICATION_ID and DISCORD_BOT_TOKEN must be set")
+	}
+
+	path := fmt.Sprintf("/applications/%s/commands", cfg.DiscordApplicationID)
+	if guildID != "" {
+		path = fmt.Sprintf("/applications/%s/guilds/%s/commands", cfg.DiscordApplicationID, guildID)
+	}
+
+	body, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+cfg.DiscordBotToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("registering commands: %s: %s", resp.Status, text)
+	}
+
+	return nil
+}
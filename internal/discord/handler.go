@@ -0,0 +1,289 @@
+package discord
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/chat"
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/6ixfigs/pingypongy/internal/players"
+	"github.com/go-chi/chi/v5"
+)
+
+// Interaction and response types, and the flag that shows a message only to
+// whoever ran the command.
+const (
+	pingInteraction    = 1
+	commandInteraction = 2
+
+	pongResponse    = 1
+	messageResponse = 4
+
+	ephemeral = 1 << 6
+)
+
+// maxContent is the longest message Discord accepts.
+const maxContent = 2000
+
+// mention matches a user mentioned in an option, such as <@80351110224678912>.
+var mention = regexp.MustCompile(`^<@!?([0-9]+)>$`)
+
+type Handler struct {
+	Rtr         chi.Router
+	api         http.Handler
+	client      *http.Client
+	publicKey   string
+	botToken    string
+	leaderboard string
+
+	// linked finds the player a Discord user's account is linked to on a
+	// leaderboard.
+	linked func(leaderboard, userID string) (string, error)
+}
+
+// NewHandler creates a handler for Discord's interactions. Commands are run
+// against api, which should be the REST API's router.
+func NewHandler(db *sql.DB, api http.Handler, cfg *config.Config) *Handler {
+	return &Handler{
+		Rtr:         chi.NewRouter(),
+		api:         api,
+		client:      &http.Client{Timeout: 2 * time.Second},
+		publicKey:   cfg.DiscordPublicKey,
+		botToken:    cfg.DiscordBotToken,
+		leaderboard: cfg.DiscordLeaderboard,
+		linked: func(leaderboard, userID string) (string, error) {
+			return players.Resolve(db, leaderboard, players.Discord, userID)
+		},
+	}
+}
+
+func (h *Handler) MountRoutes() {
+	h.Rtr.Post("/interactions", h.Interactions)
+}
+
+type user struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+}
+
+// name is the name a Discord user's player goes by: their display name, or
+// their username if they haven't set one.
+func (u *user) name() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+type commandOption struct {
+	Name    string           `json:"name"`
+	Value   any              `json:"value"`
+	Options []*commandOption `json:"options"`
+}
+
+type interaction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string           `json:"name"`
+		Options []*commandOption `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		User *user `json:"user"`
+	} `json:"member"`
+	User *user `json:"user"`
+}
+
+type response struct {
+	Type int           `json:"type"`
+	Data *responseData `json:"data,omitempty"`
+}
+
+type responseData struct {
+	Content         string         `json:"content"`
+	Flags           int            `json:"flags,omitempty"`
+	AllowedMentions map[string]any `json:"allowed_mentions"`
+}
+
+// Interactions handles Discord's pings and the /pong command.
+func (h *Handler) Interactions(w http.ResponseWriter, r *http.Request) {
+	body, err := verify(h.publicKey, r)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid signature.", http.StatusUnauthorized)
+		return
+	}
+
+	i := &interaction{}
+	if err := json.Unmarshal(body, i); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		return
+	}
+
+	switch i.Type {
+	case pingInteraction:
+		respond(w, &response{Type: pongResponse})
+	case commandInteraction:
+		respond(w, message(h.command(r.Context(), i)))
+	default:
+		http.Error(w, "Unsupported interaction.", http.StatusBadRequest)
+	}
+}
+
+// command turns the subcommand and options of the interaction into a chat
+// command, the same as the /pong Slack command, and runs it.
+func (h *Handler) command(ctx context.Context, i *interaction) *chat.Reply {
+	if len(i.Data.Options) == 0 {
+		return &chat.Reply{Text: chat.Usage}
+	}
+
+	sub := i.Data.Options[0]
+	values := map[string]string{}
+	for _, o := range sub.Options {
+		values[o.Name] = fmt.Sprint(o.Value)
+	}
+
+	invoker := i.User
+	if i.Member != nil {
+		invoker = i.Member.User
+	}
+
+	var args []string
+	switch sub.Name {
+	case "record":
+		args = []string{"record", values["player1"], values["player2"], values["score"]}
+	case "leaderboard":
+		args = []string{"leaderboard"}
+	case "stats":
+		player := values["player"]
+		if player == "" {
+			player = "me"
+		}
+		args = []string{"stats", player}
+	default:
+		args = []string{sub.Name}
+	}
+
+	for n := 1; n < len(args); n++ {
+		player, err := h.player(ctx, invoker, args[n])
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			return &chat.Reply{Text: fmt.Sprintf("Couldn't find out who %s is.\n", args[n])}
+		}
+		args[n] = player
+	}
+
+	// Only a linked account proves who ran the command.
+	var linked string
+	if invoker != nil {
+		username, err := h.linked(h.leaderboard, invoker.ID)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			return &chat.Reply{Text: "Couldn't find out who you are.\n"}
+		}
+		linked = username
+	}
+
+	return chat.Run(h.api, h.leaderboard, linked, args)
+}
+
+// player resolves an option naming a player. "me" is whoever ran the
+// command and mentions are looked up in Discord; anything else is taken to
+// be a username already.
+func (h *Handler) player(ctx context.Context, invoker *user, arg string) (string, error) {
+	if arg == "me" {
+		if invoker == nil {
+			return "", fmt.Errorf("interaction without a user")
+		}
+		return h.username(ctx, invoker)
+	}
+
+	m := mention.FindStringSubmatch(arg)
+	if m == nil {
+		return arg, nil
+	}
+
+	return h.username(ctx, &user{ID: m[1]})
+}
+
+// username finds the player a Discord user plays as: the one their account
+// is linked to, or else the one named after them in Discord. Users that are
+// only known by id are looked up.
+func (h *Handler) username(ctx context.Context, u *user) (string, error) {
+	username, err := h.linked(h.leaderboard, u.ID)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		return username, nil
+	}
+
+	if u.name() == "" {
+		if u, err = h.user(ctx, u.ID); err != nil {
+			return "", err
+		}
+	}
+	return u.name(), nil
+}
+
+// user looks up a Discord user with the bot token.
+func (h *Handler) user(ctx context.Context, id string) (*user, error) {
+	if h.botToken == "" {
+		return nil, fmt.Errorf("no bot token to look up user %s", id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/users/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bot "+h.botToken)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("looking up user %s: %s", id, resp.Status)
+	}
+
+	u := &user{}
+	if err := json.NewDecoder(resp.Body).Decode(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// message replies to a command. Nobody is pinged by the reply, even if it
+// mentions them.
+func message(rep *chat.Reply) *response {
+	content := rep.Text
+	if runes := []rune(content); len(runes) > maxContent {
+		content = string(runes[:maxContent-1]) + "…"
+	}
+
+	data := &responseData{
+		Content:         content,
+		AllowedMentions: map[string]any{"parse": []string{}},
+	}
+	if !rep.Public {
+		data.Flags = ephemeral
+	}
+
+	return &response{Type: messageResponse, Data: data}
+}
+
+func respond(w http.ResponseWriter, resp *response) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("err: %v\n", err)
+	}
+}
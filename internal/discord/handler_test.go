@@ -0,0 +1,158 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeAPI stands in for the REST API, recording matches between anyone but
+// players called nobody.
+func fakeAPI(t *testing.T) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/api/v1/leaderboards/{name}/matches", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "name") != "OnlyRealGs" {
+			t.Errorf("leaderboard = %s, want OnlyRealGs", chi.URLParam(r, "name"))
+		}
+		p1, p2 := r.FormValue("player1"), r.FormValue("player2")
+		if p1 == "nobody" || p2 == "nobody" {
+			http.Error(w, "Player nobody does not exist.", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("Match recorded: " + p1 + " " + r.FormValue("score") + " " + p2 + " by " + r.FormValue("recorded_by") + "\n"))
+	})
+	return r
+}
+
+// testHandler returns a handler whose only linked accounts are
+// 80351110224678912, linked to 2pac, and 53908232506183680, linked to eazy-e.
+func testHandler(t *testing.T, publicKey string) *Handler {
+	h := NewHandler(nil, fakeAPI(t), &config.Config{
+		DiscordPublicKey:   publicKey,
+		DiscordLeaderboard: "OnlyRealGs",
+	})
+	h.linked = func(leaderboard, userID string) (string, error) {
+		switch userID {
+		case "80351110224678912":
+			return "2pac", nil
+		case "53908232506183680":
+			return "eazy-e", nil
+		}
+		return "", nil
+	}
+	return h
+}
+
+func TestInteractionsPing(t *testing.T) {
+	publicKey, privateKey := testKey(t)
+	h := NewHandler(nil, http.NotFoundHandler(), &config.Config{DiscordPublicKey: publicKey})
+
+	w := httptest.NewRecorder()
+	h.Interactions(w, signedRequest(privateKey, "1735689600", `{"type":1}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	resp := &response{}
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != pongResponse {
+		t.Errorf("type = %d, want %d", resp.Type, pongResponse)
+	}
+}
+
+func TestInteractionsRejectsUnsigned(t *testing.T) {
+	publicKey, _ := testKey(t)
+	_, otherKey := testKey(t)
+	h := NewHandler(nil, http.NotFoundHandler(), &config.Config{DiscordPublicKey: publicKey})
+
+	w := httptest.NewRecorder()
+	h.Interactions(w, signedRequest(otherKey, "1735689600", `{"type":1}`))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestInteractionsRecord(t *testing.T) {
+	publicKey, privateKey := testKey(t)
+	h := testHandler(t, publicKey)
+
+	tests := []struct {
+		name      string
+		userID    string
+		player2   string
+		wantText  string
+		wantFlags int
+	}{
+		{
+			name:     "recorded",
+			userID:   "80351110224678912",
+			player2:  "<@53908232506183680>",
+			wantText: "Match recorded: 2pac 2-1 eazy-e by 2pac\n",
+		},
+		{
+			name:     "not linked",
+			userID:   "41771983423143937",
+			player2:  "eazy-e",
+			wantText: "Match recorded: Ice Cube 2-1 eazy-e by \n",
+		},
+		{
+			name:      "failed",
+			userID:    "80351110224678912",
+			player2:   "nobody",
+			wantText:  "Player nobody does not exist.\n",
+			wantFlags: ephemeral,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(map[string]any{
+				"type": commandInteraction,
+				"data": map[string]any{
+					"name": "pong",
+					"options": []map[string]any{{
+						"name": "record",
+						"options": []map[string]any{
+							{"name": "player1", "value": "me"},
+							{"name": "player2", "value": tt.player2},
+							{"name": "score", "value": "2-1"},
+						},
+					}},
+				},
+				"member": map[string]any{
+					"user": map[string]any{"id": tt.userID, "username": "icecube", "global_name": "Ice Cube"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			h.Interactions(w, signedRequest(privateKey, "1735689600", string(body)))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			resp := &response{}
+			if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Type != messageResponse {
+				t.Fatalf("type = %d, want %d", resp.Type, messageResponse)
+			}
+			if resp.Data.Content != tt.wantText {
+				t.Errorf("content = %q, want %q", resp.Data.Content, tt.wantText)
+			}
+			if resp.Data.Flags != tt.wantFlags {
+				t.Errorf("flags = %d, want %d", resp.Data.Flags, tt.wantFlags)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package discord
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// The headers Discord signs its requests with.
+const (
+	SignatureHeader = "X-Signature-Ed25519"
+	TimestampHeader = "X-Signature-Timestamp"
+)
+
+// maxBodySize is the largest request Discord is expected to send.
+const maxBodySize = 1 << 20
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// verify checks that the request was signed with the private key of the
+// Discord application, whose public key is given in hex. It reads the body
+// and puts it back.
+func verify(publicKey string, r *http.Request) ([]byte, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key")
+	}
+
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, ErrMissingSignature
+	}
+
+	timestamp := r.Header.Get(TimestampHeader)
+	if timestamp == "" {
+		return nil, ErrMissingSignature
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !ed25519.Verify(key, append([]byte(timestamp), body...), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	return body, nil
+}
@@ -0,0 +1,124 @@
+package discord
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testKey generates the key pair of a Discord application, returning the
+// public key in hex as it is configured.
+func testKey(t *testing.T) (string, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(public), private
+}
+
+// signedRequest returns a request signed with the key the way Discord signs
+// them.
+func signedRequest(key ed25519.PrivateKey, timestamp, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/interactions", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey := testKey(t)
+	_, otherKey := testKey(t)
+	body := `{"type":1}`
+	ts := "1735689600"
+
+	r := signedRequest(privateKey, ts, body)
+	got, err := verify(publicKey, r)
+	if err != nil {
+		t.Fatalf("verify() = %v, want nil", err)
+	}
+	if string(got) != body {
+		t.Errorf("verify() body = %s, want %s", got, body)
+	}
+	if again, _ := io.ReadAll(r.Body); string(again) != body {
+		t.Errorf("r.Body = %s, want %s", again, body)
+	}
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want error
+	}{
+		{
+			name: "other key",
+			req:  func() *http.Request { return signedRequest(otherKey, ts, body) },
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "tampered body",
+			req: func() *http.Request {
+				r := signedRequest(privateKey, ts, body)
+				r.Body = io.NopCloser(strings.NewReader(`{"type":2}`))
+				return r
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "tampered timestamp",
+			req: func() *http.Request {
+				r := signedRequest(privateKey, ts, body)
+				r.Header.Set(TimestampHeader, "1735689601")
+				return r
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "bad hex signature",
+			req: func() *http.Request {
+				r := signedRequest(privateKey, ts, body)
+				r.Header.Set(SignatureHeader, "not-hex")
+				return r
+			},
+			want: ErrMissingSignature,
+		},
+		{
+			name: "short signature",
+			req: func() *http.Request {
+				r := signedRequest(privateKey, ts, body)
+				r.Header.Set(SignatureHeader, r.Header.Get(SignatureHeader)[:64])
+				return r
+			},
+			want: ErrMissingSignature,
+		},
+		{
+			name: "missing timestamp",
+			req: func() *http.Request {
+				r := signedRequest(privateKey, ts, body)
+				r.Header.Del(TimestampHeader)
+				return r
+			},
+			want: ErrMissingSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verify(publicKey, tt.req()); !errors.Is(err, tt.want) {
+				t.Errorf("verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyInvalidPublicKey(t *testing.T) {
+	_, privateKey := testKey(t)
+
+	if _, err := verify("not a key", signedRequest(privateKey, "1735689600", `{"type":1}`)); err == nil {
+		t.Error("verify() = nil, want an error")
+	}
+}
@@ -0,0 +1,598 @@
+package ladder
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/6ixfigs/pingypongy/internal/matches"
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type Handler struct {
+	Rtr chi.Router
+	db  *sql.DB
+}
+
+func NewHandler(db *sql.DB) *Handler {
+	return &Handler{
+		Rtr: chi.NewRouter(),
+		db:  db,
+	}
+}
+
+func (h *Handler) MountRoutes() {
+	h.Rtr.Post("/challenges", h.Challenge)
+	h.Rtr.Get("/challenges", h.Challenges)
+	h.Rtr.Post("/challenges/{challenge_id}/accept", h.Accept)
+	h.Rtr.Post("/challenges/{challenge_id}/matches", h.Play)
+	h.Rtr.Get("/history", h.History)
+}
+
+func (h *Handler) Challenge(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	challengerName := r.FormValue("challenger")
+	challengedName := r.FormValue("challenged")
+
+	if challengerName == challengedName {
+		render.Error(w, r, problem.New(problem.SamePlayer, "Player can't challenge himself."))
+		return
+	}
+
+	Expire(h.db, name)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	l, err := ladder(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	c := &models.Challenge{LeaderboardID: l.ID}
+
+	c.Challenger, err = player(tx, l, challengerName)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	c.Challenged, err = player(tx, l, challengedName)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	places := c.Challenger.LadderPosition - c.Challenged.LadderPosition
+	if places <= 0 || places > l.ChallengeRange {
+		render.Error(w, r, problem.New(problem.OutOfChallengeRange, fmt.Sprintf("%s can only challenge players up to %d places above them.", challengerName, l.ChallengeRange)))
+		return
+	}
+
+	query := `
+	SELECT COUNT(*) FROM challenges
+	WHERE status IN ($1, $2) AND (challenger_id IN ($3, $4) OR challenged_id IN ($3, $4))
+	`
+	var open int
+	err = tx.QueryRow(query, Pending, Accepted, c.Challenger.ID, c.Challenged.ID).Scan(&open)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	if open > 0 {
+		render.Error(w, r, problem.New(problem.ChallengeOpen, fmt.Sprintf("%s or %s already has an open challenge.", challengerName, challengedName)))
+		return
+	}
+
+	query = `
+	INSERT INTO challenges (leaderboard_id, challenger_id, challenged_id, expires_at)
+	VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(days => $4))
+	RETURNING id, status, to_char(expires_at, 'YYYY-MM-DD HH24:MI')
+	`
+	err = tx.QueryRow(query, l.ID, c.Challenger.ID, c.Challenged.ID, l.ChallengeExpiryDays).Scan(
+		&c.ID,
+		&c.Status,
+		&c.ExpiresAt,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Challenge #%d: %s (#%d) challenged %s (#%d) on ladder %s! Play by %s.\n",
+		c.ID,
+		c.Challenger.Username,
+		c.Challenger.LadderPosition,
+		c.Challenged.Username,
+		c.Challenged.LadderPosition,
+		l.Name,
+		c.ExpiresAt,
+	)
+
+	e := webhooks.NewEvent(webhook.ChallengeIssued, l.Name, response)
+	e.Challenge = webhooks.Challenge(c)
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, c)
+}
+
+func (h *Handler) Challenges(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	l, err := find(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	SELECT c.id, p1.username, p1.ladder_position, p2.username, p2.ladder_position, c.status, to_char(c.expires_at, 'YYYY-MM-DD HH24:MI')
+	FROM challenges c
+	JOIN players p1 ON p1.id = c.challenger_id
+	JOIN players p2 ON p2.id = c.challenged_id
+	WHERE c.leaderboard_id = $1 AND c.status IN ($2, $3) AND c.expires_at > CURRENT_TIMESTAMP
+	ORDER BY c.created_at
+	`
+
+	rows, err := tx.Query(query, l.ID, Pending, Accepted)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"id", "challenger", "#", "challenged", "#", "status", "expires"})
+	challenges := []*models.Challenge{}
+	for rows.Next() {
+		c := &models.Challenge{Challenger: &models.Player{}, Challenged: &models.Player{}}
+		err = rows.Scan(
+			&c.ID,
+			&c.Challenger.Username,
+			&c.Challenger.LadderPosition,
+			&c.Challenged.Username,
+			&c.Challenged.LadderPosition,
+			&c.Status,
+			&c.ExpiresAt,
+		)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+
+		t.AppendRow(table.Row{
+			c.ID,
+			c.Challenger.Username,
+			c.Challenger.LadderPosition,
+			c.Challenged.Username,
+			c.Challenged.LadderPosition,
+			c.Status,
+			c.ExpiresAt,
+		})
+		challenges = append(challenges, c)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	var response string
+	if len(challenges) > 0 {
+		response = fmt.Sprintf("Open challenges on ladder %s:\n```\n%s\n```\n", l.Name, t.Render())
+	} else {
+		response = "No open challenges.\n"
+	}
+
+	render.Respond(w, r, response, challenges)
+}
+
+func (h *Handler) Accept(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+
+	Expire(h.db, name)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	l, err := ladder(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	c, err := challenge(tx, l, chi.URLParam(r, "challenge_id"))
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	if c.Status != Pending {
+		render.Error(w, r, problem.New(problem.ChallengeNotPending, fmt.Sprintf("Challenge #%d is already %s.", c.ID, c.Status)))
+		return
+	}
+
+	query := `
+	UPDATE challenges
+	SET status = $1, accepted_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	`
+	_, err = tx.Exec(query, Accepted, c.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Challenge #%d: %s accepted %s's challenge on ladder %s! Play by %s.\n",
+		c.ID,
+		c.Challenged.Username,
+		c.Challenger.Username,
+		l.Name,
+		c.ExpiresAt,
+	)
+
+	e := webhooks.NewEvent(webhook.ChallengeAccepted, l.Name, response)
+	e.Challenge = webhooks.Challenge(c)
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, c)
+}
+
+func (h *Handler) Play(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	score := r.FormValue("score")
+
+	Expire(h.db, name)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	l, err := ladder(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	c, err := challenge(tx, l, chi.URLParam(r, "challenge_id"))
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	if c.Status != Accepted {
+		render.Error(w, r, problem.New(problem.ChallengeNotAccepted, fmt.Sprintf("Challenge #%d is %s, only accepted challenges can be played.", c.ID, c.Status)))
+		return
+	}
+
+	result, err := matches.Record(tx, l, c.Challenger.Username, c.Challenged.Username, score, "")
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	c.MatchID = result.ID
+	challengerWon := result.Score.P1 > result.Score.P2
+	defendedPosition := c.Challenged.LadderPosition
+
+	err = resolve(tx, c, Played, challengerWon)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := matches.Summary(result)
+	if challengerWon {
+		response += fmt.Sprintf("%s takes #%d from %s on ladder %s!\n", c.Challenger.Username, defendedPosition, c.Challenged.Username, l.Name)
+	} else {
+		response += fmt.Sprintf("%s defends #%d against %s on ladder %s!\n", c.Challenged.Username, defendedPosition, c.Challenger.Username, l.Name)
+	}
+
+	e := webhooks.NewEvent(webhook.ChallengePlayed, l.Name, response)
+	e.Match = webhooks.Match(result)
+	e.Challenge = webhooks.Challenge(c)
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.ChallengeResult{Match: result, Challenge: c})
+}
+
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	username := r.FormValue("player")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	l, err := find(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	SELECT p.username, h.old_position, h.new_position, h.challenge_id, to_char(h.changed_at, 'YYYY-MM-DD HH24:MI')
+	FROM ladder_history h
+	JOIN players p ON p.id = h.player_id
+	WHERE p.leaderboard_id = $1 AND ($2 = '' OR p.username = $2)
+	ORDER BY h.changed_at DESC, h.id DESC
+	LIMIT 50
+	`
+
+	rows, err := tx.Query(query, l.ID, username)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"changed", "player", "from", "to", "challenge"})
+	changes := []*models.LadderChange{}
+	for rows.Next() {
+		change := &models.LadderChange{Player: &models.Player{}}
+		var challengeID sql.NullInt64
+		err = rows.Scan(
+			&change.Player.Username,
+			&change.OldPosition,
+			&change.NewPosition,
+			&challengeID,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+		change.ChallengeID = int(challengeID.Int64)
+
+		t.AppendRow(table.Row{
+			change.ChangedAt,
+			change.Player.Username,
+			change.OldPosition,
+			change.NewPosition,
+			change.ChallengeID,
+		})
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	var response string
+	if len(changes) > 0 {
+		response = fmt.Sprintf("Position history on ladder %s:\n```\n%s\n```\n", l.Name, t.Render())
+	} else {
+		response = "No position changes yet.\n"
+	}
+
+	render.Respond(w, r, response, changes)
+}
+
+// find looks up the ladder leaderboard without locking it.
+func find(tx *sql.Tx, name string) (*models.Leaderboard, error) {
+	query := `
+	SELECT id, name, type, challenge_range, challenge_expiry_days FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err := tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+		&l.Type,
+		&l.ChallengeRange,
+		&l.ChallengeExpiryDays,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name))
+		}
+		return nil, err
+	}
+
+	if l.Type != Ladder {
+		return nil, problem.New(problem.NotALadder, fmt.Sprintf("Leaderboard %s is not a ladder.", name))
+	}
+
+	return l, nil
+}
+
+// ladder locks the ladder leaderboard so that positions can't change under
+// a request. Leaderboards that aren't ladders are never locked.
+func ladder(tx *sql.Tx, name string) (*models.Leaderboard, error) {
+	l, err := find(tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT id FROM leaderboards
+	WHERE id = $1
+	FOR UPDATE
+	`
+
+	if _, err := tx.Exec(query, l.ID); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func player(tx *sql.Tx, l *models.Leaderboard, username string) (*models.Player, error) {
+	query := `
+	SELECT id, username, ladder_position FROM players
+	WHERE leaderboard_id = $1 AND username = $2
+	`
+
+	p := &models.Player{}
+	err := tx.QueryRow(query, l.ID, username).Scan(
+		&p.ID,
+		&p.Username,
+		&p.LadderPosition,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, l.Name))
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func challenge(tx *sql.Tx, l *models.Leaderboard, id string) (*models.Challenge, error) {
+	challengeID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid challenge id: %s.", id))
+	}
+
+	query := `
+	SELECT c.id, p1.id, p1.username, p1.ladder_position, p2.id, p2.username, p2.ladder_position, c.status, to_char(c.expires_at, 'YYYY-MM-DD HH24:MI')
+	FROM challenges c
+	JOIN players p1 ON p1.id = c.challenger_id
+	JOIN players p2 ON p2.id = c.challenged_id
+	WHERE c.leaderboard_id = $1 AND c.id = $2
+	`
+
+	c := &models.Challenge{LeaderboardID: l.ID, Challenger: &models.Player{}, Challenged: &models.Player{}}
+	err = tx.QueryRow(query, l.ID, challengeID).Scan(
+		&c.ID,
+		&c.Challenger.ID,
+		&c.Challenger.Username,
+		&c.Challenger.LadderPosition,
+		&c.Challenged.ID,
+		&c.Challenged.Username,
+		&c.Challenged.LadderPosition,
+		&c.Status,
+		&c.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, problem.New(problem.ChallengeNotFound, fmt.Sprintf("Challenge #%d does not exist on ladder %s.", challengeID, l.Name))
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
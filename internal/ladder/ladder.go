@@ -0,0 +1,212 @@
+package ladder
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+const (
+	Elo    = "elo"
+	Ladder = "ladder"
+)
+
+const (
+	Pending   = "pending"
+	Accepted  = "accepted"
+	Played    = "played"
+	Forfeited = "forfeited"
+)
+
+// forfeit closes the open challenges on the ladder that weren't played in
+// time. The challenged player forfeits, so the challenger takes their place.
+func forfeit(tx *sql.Tx, l *models.Leaderboard) error {
+	query := `
+	SELECT c.id, p1.id, p1.username, p1.ladder_position, p2.id, p2.username, p2.ladder_position
+	FROM challenges c
+	JOIN players p1 ON p1.id = c.challenger_id
+	JOIN players p2 ON p2.id = c.challenged_id
+	WHERE c.leaderboard_id = $1 AND c.status IN ($2, $3) AND c.expires_at <= CURRENT_TIMESTAMP
+	ORDER BY c.expires_at
+	FOR UPDATE OF c
+	`
+
+	rows, err := tx.Query(query, l.ID, Pending, Accepted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var expired []*models.Challenge
+	for rows.Next() {
+		c := &models.Challenge{Challenger: &models.Player{}, Challenged: &models.Player{}}
+		err := rows.Scan(
+			&c.ID,
+			&c.Challenger.ID,
+			&c.Challenger.Username,
+			&c.Challenger.LadderPosition,
+			&c.Challenged.ID,
+			&c.Challenged.Username,
+			&c.Challenged.LadderPosition,
+		)
+		if err != nil {
+			return err
+		}
+		expired = append(expired, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range expired {
+		if err := resolve(tx, c, Forfeited, true); err != nil {
+			return err
+		}
+
+		message := fmt.Sprintf("%s didn't play %s's challenge in time and forfeits. %s moves up to #%d on ladder %s!\n",
+			c.Challenged.Username,
+			c.Challenger.Username,
+			c.Challenger.Username,
+			c.Challenger.LadderPosition,
+			l.Name,
+		)
+		e := webhooks.NewEvent(webhook.ChallengeForfeited, l.Name, message)
+		e.Challenge = webhooks.Challenge(c)
+		if err := webhooks.Enqueue(tx, l.ID, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve closes the challenge with the given status. If the challenger won,
+// the two players swap places on the ladder and the move is recorded in the
+// position history.
+func resolve(tx *sql.Tx, c *models.Challenge, status string, challengerWon bool) error {
+	query := `
+	UPDATE challenges
+	SET status = $1, match_id = $2, resolved_at = CURRENT_TIMESTAMP
+	WHERE id = $3
+	`
+
+	matchID := sql.NullInt64{Int64: int64(c.MatchID), Valid: c.MatchID != 0}
+	if _, err := tx.Exec(query, status, matchID, c.ID); err != nil {
+		return err
+	}
+	c.Status = status
+
+	if !challengerWon || c.Challenger.LadderPosition < c.Challenged.LadderPosition {
+		return nil
+	}
+
+	query = `
+	UPDATE players
+	SET ladder_position = $1
+	WHERE id = $2
+	`
+
+	historyQuery := `
+	INSERT INTO ladder_history (player_id, challenge_id, old_position, new_position)
+	VALUES ($1, $2, $3, $4)
+	`
+
+	challengerPosition, challengedPosition := c.Challenger.LadderPosition, c.Challenged.LadderPosition
+	for _, move := range []struct {
+		player   *models.Player
+		position int
+	}{
+		{c.Challenger, challengedPosition},
+		{c.Challenged, challengerPosition},
+	} {
+		if _, err := tx.Exec(query, move.position, move.player.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(historyQuery, move.player.ID, c.ID, move.player.LadderPosition, move.position); err != nil {
+			return err
+		}
+		move.player.LadderPosition = move.position
+	}
+
+	return nil
+}
+
+// Sweep expires the challenges on every ladder at the given interval. It
+// never returns.
+func Sweep(db *sql.DB, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := sweep(db); err != nil {
+			log.Printf("err: %v\n", err)
+		}
+	}
+}
+
+func sweep(db *sql.DB) error {
+	query := `
+	SELECT name FROM leaderboards
+	WHERE type = $1
+	`
+
+	rows, err := db.Query(query, Ladder)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var ladders []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		ladders = append(ladders, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range ladders {
+		Expire(db, name)
+	}
+
+	return nil
+}
+
+// Expire forfeits the expired challenges on the ladder in a transaction of
+// its own, so that they stick even if the request that triggered it fails.
+// Leaderboards that aren't ladders are left alone.
+func Expire(db *sql.DB, name string) {
+	err := func() (err error) {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			} else {
+				tx.Commit()
+			}
+		}()
+
+		l, err := ladder(tx, name)
+		if err != nil {
+			return err
+		}
+
+		return forfeit(tx, l)
+	}()
+	if err != nil {
+		var p *problem.Problem
+		if !errors.As(err, &p) {
+			log.Printf("err: %v\n", err)
+		}
+	}
+}
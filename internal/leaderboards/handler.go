@@ -5,9 +5,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/6ixfigs/pingypongy/internal/ladder"
+	"github.com/6ixfigs/pingypongy/internal/matches"
 	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
 	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/lib/pq"
@@ -28,21 +34,46 @@ func NewHandler(db *sql.DB) *Handler {
 func (h *Handler) MountRoutes() {
 	h.Rtr.Post("/", h.Create)
 	h.Rtr.Get("/{leaderboard_name}", h.Get)
+	h.Rtr.Get("/{leaderboard_name}/predict", h.Predict)
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
 		return
 	}
 
 	name := r.FormValue("name")
 
+	leaderboardType := r.FormValue("type")
+	switch leaderboardType {
+	case "":
+		leaderboardType = ladder.Elo
+	case ladder.Elo, ladder.Ladder:
+	default:
+		render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid type: %s. Use %s or %s.", leaderboardType, ladder.Elo, ladder.Ladder)))
+		return
+	}
+
+	challengeRange, expiryDays := 3, 7
+	for field, value := range map[string]*int{"challenge_range": &challengeRange, "expiry_days": &expiryDays} {
+		if r.FormValue(field) == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(r.FormValue(field))
+		if err != nil || n < 1 {
+			render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid %s: must be a positive number.", field)))
+			return
+		}
+		*value = n
+	}
+
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -54,30 +85,38 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	query := `
-	INSERT INTO leaderboards (name)
-	VALUES ($1)
+	INSERT INTO leaderboards (name, type, challenge_range, challenge_expiry_days)
+	VALUES ($1, $2, $3, $4)
 	`
 
-	_, err = tx.Exec(query, name)
+	_, err = tx.Exec(query, name, leaderboardType, challengeRange, expiryDays)
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err, ok := err.(*pq.Error); ok {
 			if err.Code.Name() == "unique_violation" {
-				http.Error(w, fmt.Sprintf("Leaderboard %s already exists.", name), http.StatusConflict)
+				render.Error(w, r, problem.New(problem.LeaderboardExists, fmt.Sprintf("Leaderboard %s already exists.", name)))
 				return
 			}
-			http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+			render.Error(w, r, err)
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
 	response := fmt.Sprintf("Created leaderboard: %s\n", name)
+	if leaderboardType == ladder.Ladder {
+		response = fmt.Sprintf("Created ladder: %s\n", name)
+	}
 
 	log.Print(response)
 
-	w.Write([]byte(response))
+	render.Respond(w, r, response, &models.Leaderboard{
+		Name:                name,
+		Type:                leaderboardType,
+		ChallengeRange:      challengeRange,
+		ChallengeExpiryDays: expiryDays,
+	})
 }
 
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
@@ -86,7 +125,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -98,39 +137,46 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	query := `
-	SELECT id, name FROM leaderboards
+	SELECT id, name, type FROM leaderboards
 	WHERE name = $1
 	`
 	l := &models.Leaderboard{}
 	err = tx.QueryRow(query, name).Scan(
 		&l.ID,
 		&l.Name,
+		&l.Type,
 	)
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
-	query = `
+	// Ladders are ordered by challenge results rather than by rating.
+	orderBy := "elo DESC"
+	if l.Type == ladder.Ladder {
+		orderBy = "ladder_position"
+	}
+
+	query = fmt.Sprintf(`
 	SELECT username, matches_won, matches_drawn, matches_lost, elo
 	FROM players
 	WHERE leaderboard_id = $1
-	ORDER BY elo DESC
-	`
+	ORDER BY %s
+	`, orderBy)
 
 	rows, err := tx.Query(query, l.ID)
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
-	var rankings []models.Player
+	rankings := []models.Player{}
 	for rows.Next() {
 		player := models.Player{}
 		err = rows.Scan(
@@ -142,7 +188,7 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		)
 		if err != nil {
 			log.Printf("err: %v\n", err)
-			http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+			render.Error(w, r, err)
 			return
 		}
 
@@ -150,12 +196,13 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 	if err = rows.Err(); err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
 	t := table.NewWriter()
 	t.AppendHeader(table.Row{"#", "player", "W", "D", "L", "P", "Win Ratio", "Elo"})
+	e := webhooks.NewEvent(webhook.LeaderboardViewed, l.Name, "")
 	for rank, player := range rankings {
 		matchesPlayed := player.MatchesWon + player.MatchesDrawn + player.MatchesLost
 		winRatio := 0.
@@ -172,16 +219,142 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 			fmt.Sprintf("%.2f%%", winRatio),
 			player.Elo,
 		})
+
+		p := webhooks.Player(&player)
+		p.Rank = rank + 1
+		e.Players = append(e.Players, p)
 	}
 
 	response := fmt.Sprintf("Leaderboard %s:\n```\n%s\n```\n", l.Name, t.Render())
+	e.Text = response
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
 
-	go func() {
-		urls, err := webhooks.All(h.db, name)
-		if err == nil {
-			webhooks.Notify(urls, response)
+	render.Respond(w, r, response, &models.Standings{Leaderboard: l, Players: rankings})
+}
+
+func (h *Handler) Predict(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	username1 := r.FormValue("player1")
+	username2 := r.FormValue("player2")
+
+	if username1 == username2 {
+		render.Error(w, r, problem.New(problem.SamePlayer, "Player can't play against himself."))
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
 		}
 	}()
 
-	w.Write([]byte(response))
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	query = `
+	SELECT username, elo FROM players
+	WHERE leaderboard_id = $1 AND username = $2
+	`
+	players := make([]*models.Player, 2)
+	for i, username := range []string{username1, username2} {
+		players[i] = &models.Player{}
+		err = tx.QueryRow(query, l.ID, username).Scan(
+			&players[i].Username,
+			&players[i].Elo,
+		)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			if err == sql.ErrNoRows {
+				render.Error(w, r, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, name)))
+				return
+			}
+			render.Error(w, r, err)
+			return
+		}
+	}
+
+	// Every match is counted once for each of its players, so the ratio of
+	// the sums is the share of matches that ended in a draw.
+	query = `
+	SELECT
+		COALESCE(SUM(matches_drawn), 0),
+		COALESCE(SUM(matches_won + matches_drawn + matches_lost), 0)
+	FROM players
+	WHERE leaderboard_id = $1
+	`
+	var drawn, played int
+	err = tx.QueryRow(query, l.ID).Scan(&drawn, &played)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	drawRate := 0.
+	if played > 0 {
+		drawRate = float64(drawn) / float64(played)
+	}
+
+	p := matches.Predict(players[0], players[1], drawRate)
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"player", "Elo", "Expected", "Win %", "Win", "Draw", "Loss"})
+	t.AppendRow(table.Row{
+		p.P1.Username,
+		p.P1.Elo,
+		fmt.Sprintf("%.2f", p.P1ExpectedScore),
+		fmt.Sprintf("%.2f%%", p.P1WinProbability*100),
+		fmt.Sprintf("%+d", p.P1EloChange.Win),
+		fmt.Sprintf("%+d", p.P1EloChange.Draw),
+		fmt.Sprintf("%+d", p.P1EloChange.Loss),
+	})
+	t.AppendRow(table.Row{
+		p.P2.Username,
+		p.P2.Elo,
+		fmt.Sprintf("%.2f", p.P2ExpectedScore),
+		fmt.Sprintf("%.2f%%", p.P2WinProbability*100),
+		fmt.Sprintf("%+d", p.P2EloChange.Win),
+		fmt.Sprintf("%+d", p.P2EloChange.Draw),
+		fmt.Sprintf("%+d", p.P2EloChange.Loss),
+	})
+
+	response := fmt.Sprintf("Prediction for %s vs %s on leaderboard %s:\n```\n%s\n```\nDraw probability: %.2f%%\n",
+		p.P1.Username,
+		p.P2.Username,
+		l.Name,
+		t.Render(),
+		p.DrawProbability*100,
+	)
+
+	render.Respond(w, r, response, p)
 }
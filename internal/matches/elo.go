@@ -0,0 +1,85 @@
+package matches
+
+import (
+	"math"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// Predict returns the expected outcome of a match between p1 and p2 without
+// recording it. drawRate is the share of matches on the leaderboard that ended
+// in a draw and is used to split the expected score into win, draw and loss
+// probabilities.
+func Predict(p1, p2 *models.Player, drawRate float64) *models.Prediction {
+	e1 := expectedScore(p1.Elo, p2.Elo)
+	e2 := expectedScore(p2.Elo, p1.Elo)
+
+	// An expected score counts a draw as half a win, so the draw probability
+	// can't exceed twice the smaller expected score.
+	pDraw := math.Min(drawRate, 2*math.Min(e1, e2))
+
+	return &models.Prediction{
+		P1:               p1,
+		P2:               p2,
+		P1ExpectedScore:  e1,
+		P2ExpectedScore:  e2,
+		P1WinProbability: e1 - pDraw/2,
+		P2WinProbability: e2 - pDraw/2,
+		DrawProbability:  pDraw,
+		P1EloChange:      eloChange(p1, p2),
+		P2EloChange:      eloChange(p2, p1),
+	}
+}
+
+func eloChange(player, opponent *models.Player) *models.EloChange {
+	p, o := *player, *opponent
+	updateElo(&p, &o, false)
+	win := p.Elo - player.Elo
+
+	p, o = *player, *opponent
+	updateElo(&p, &o, true)
+	draw := p.Elo - player.Elo
+
+	p, o = *player, *opponent
+	updateElo(&o, &p, false)
+	loss := p.Elo - player.Elo
+
+	return &models.EloChange{
+		Win:  win,
+		Draw: draw,
+		Loss: loss,
+	}
+}
+
+func expectedScore(rating, opponentRating int) float64 {
+	q := math.Pow(10, float64(rating)/400)
+	qOpponent := math.Pow(10, float64(opponentRating)/400)
+
+	return q / (q + qOpponent)
+}
+
+func kFactor(rating int) float64 {
+	if rating < 2100 {
+		return 32
+	}
+	if rating >= 2100 && rating < 2400 {
+		return 24
+	}
+	return 16
+}
+
+func updateElo(winner, loser *models.Player, isDraw bool) {
+	eW := expectedScore(winner.Elo, loser.Elo)
+	eL := expectedScore(loser.Elo, winner.Elo)
+
+	kW := kFactor(winner.Elo)
+	kL := kFactor(loser.Elo)
+
+	sW, sL := 1.0, 0.0
+	if isDraw {
+		sW, sL = 0.5, 0.5
+	}
+
+	winner.Elo = winner.Elo + int(math.Round(kW*(sW-eW)))
+	loser.Elo = loser.Elo + int(math.Round(kL*(sL-eL)))
+}
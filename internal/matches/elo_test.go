@@ -0,0 +1,138 @@
+package matches
+
+import (
+	"math"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+const epsilon = 1e-9
+
+func TestExpectedScore(t *testing.T) {
+	tests := []struct {
+		rating, opponentRating int
+		want                   float64
+	}{
+		{rating: 1000, opponentRating: 1000, want: 0.5},
+		{rating: 1400, opponentRating: 1000, want: 10. / 11},
+		{rating: 1000, opponentRating: 1400, want: 1. / 11},
+		{rating: 1800, opponentRating: 1000, want: 100. / 101},
+	}
+
+	for _, tt := range tests {
+		if got := expectedScore(tt.rating, tt.opponentRating); math.Abs(got-tt.want) > epsilon {
+			t.Errorf("expectedScore(%d, %d) = %f, want %f", tt.rating, tt.opponentRating, got, tt.want)
+		}
+	}
+}
+
+func TestKFactor(t *testing.T) {
+	tests := []struct {
+		rating int
+		want   float64
+	}{
+		{rating: 1000, want: 32},
+		{rating: 2099, want: 32},
+		{rating: 2100, want: 24},
+		{rating: 2399, want: 24},
+		{rating: 2400, want: 16},
+	}
+
+	for _, tt := range tests {
+		if got := kFactor(tt.rating); got != tt.want {
+			t.Errorf("kFactor(%d) = %f, want %f", tt.rating, got, tt.want)
+		}
+	}
+}
+
+func TestPredict(t *testing.T) {
+	tests := []struct {
+		name       string
+		elo1, elo2 int
+		drawRate   float64
+		win1, draw float64
+		change1    models.EloChange
+		change2    models.EloChange
+	}{
+		{
+			name:    "equal ratings",
+			elo1:    1000,
+			elo2:    1000,
+			win1:    0.5,
+			change1: models.EloChange{Win: 16, Draw: 0, Loss: -16},
+			change2: models.EloChange{Win: 16, Draw: 0, Loss: -16},
+		},
+		{
+			name:     "equal ratings with draws",
+			elo1:     1000,
+			elo2:     1000,
+			drawRate: 0.2,
+			win1:     0.4,
+			draw:     0.2,
+			change1:  models.EloChange{Win: 16, Draw: 0, Loss: -16},
+			change2:  models.EloChange{Win: 16, Draw: 0, Loss: -16},
+		},
+		{
+			name:    "400 points apart",
+			elo1:    1400,
+			elo2:    1000,
+			win1:    10. / 11,
+			change1: models.EloChange{Win: 3, Draw: -13, Loss: -29},
+			change2: models.EloChange{Win: 29, Draw: 13, Loss: -3},
+		},
+		{
+			// The underdog can't draw more often than twice their expected
+			// score.
+			name:     "draw rate capped",
+			elo1:     1400,
+			elo2:     1000,
+			drawRate: 0.5,
+			win1:     10./11 - 1./11,
+			draw:     2. / 11,
+			change1:  models.EloChange{Win: 3, Draw: -13, Loss: -29},
+			change2:  models.EloChange{Win: 29, Draw: 13, Loss: -3},
+		},
+		{
+			name:    "lower K-factor above 2100",
+			elo1:    2200,
+			elo2:    2200,
+			win1:    0.5,
+			change1: models.EloChange{Win: 12, Draw: 0, Loss: -12},
+			change2: models.EloChange{Win: 12, Draw: 0, Loss: -12},
+		},
+		{
+			name:    "lowest K-factor from 2400",
+			elo1:    2400,
+			elo2:    2000,
+			win1:    10. / 11,
+			change1: models.EloChange{Win: 1, Draw: -7, Loss: -15},
+			change2: models.EloChange{Win: 29, Draw: 13, Loss: -3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Predict(&models.Player{Elo: tt.elo1}, &models.Player{Elo: tt.elo2}, tt.drawRate)
+
+			if math.Abs(p.P1ExpectedScore+p.P2ExpectedScore-1) > epsilon {
+				t.Errorf("expected scores = %f and %f, want them to add up to 1", p.P1ExpectedScore, p.P2ExpectedScore)
+			}
+			if math.Abs(p.P1WinProbability+p.DrawProbability+p.P2WinProbability-1) > epsilon {
+				t.Errorf("probabilities = %f, %f and %f, want them to add up to 1", p.P1WinProbability, p.DrawProbability, p.P2WinProbability)
+			}
+			if math.Abs(p.P1WinProbability-tt.win1) > epsilon {
+				t.Errorf("win probability = %f, want %f", p.P1WinProbability, tt.win1)
+			}
+			if math.Abs(p.DrawProbability-tt.draw) > epsilon {
+				t.Errorf("draw probability = %f, want %f", p.DrawProbability, tt.draw)
+			}
+			if *p.P1EloChange != tt.change1 {
+				t.Errorf("player 1's Elo change = %+v, want %+v", *p.P1EloChange, tt.change1)
+			}
+			if *p.P2EloChange != tt.change2 {
+				t.Errorf("player 2's Elo change = %+v, want %+v", *p.P2EloChange, tt.change2)
+			}
+		})
+	}
+}
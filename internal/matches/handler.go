@@ -4,13 +4,15 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
 	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -28,12 +30,14 @@ func NewHandler(db *sql.DB) *Handler {
 
 func (h *Handler) MountRoutes() {
 	h.Rtr.Post("/", h.Record)
+	h.Rtr.Post("/{match_id}/confirm", h.Confirm)
+	h.Rtr.Post("/{match_id}/dispute", h.Dispute)
 }
 
 func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
 		return
 	}
 
@@ -41,23 +45,94 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 	username1 := r.FormValue("player1")
 	username2 := r.FormValue("player2")
 	score := r.FormValue("score")
+	recordedBy := r.FormValue("recorded_by")
 
-	if username1 == username2 {
-		http.Error(w, "Player can't play against himself.", http.StatusBadRequest)
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
 		return
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
 
-	matchScore, err := parseScore(score)
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	leaderboard := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&leaderboard.ID,
+		&leaderboard.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	result, err := Record(tx, leaderboard, username1, username2, score, recordedBy)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := Summary(result)
+
+	e := webhooks.NewEvent(webhook.MatchRecorded, leaderboard.Name, response)
+	e.Match = webhooks.Match(result)
+
+	err = webhooks.Enqueue(tx, leaderboard.ID, e)
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, fmt.Sprintf("Invalid score: %s.\n", err.Error()), http.StatusBadRequest)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, result)
+}
+
+func (h *Handler) Confirm(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, Confirmed)
+}
+
+func (h *Handler) Dispute(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, Disputed)
+}
+
+func (h *Handler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	username := r.FormValue("player")
+
+	matchID, err := strconv.Atoi(chi.URLParam(r, "match_id"))
+	if err != nil {
+		render.Error(w, r, problem.New(problem.MatchNotFound, fmt.Sprintf("Match %s does not exist on %s leaderboard.", chi.URLParam(r, "match_id"), name)))
 		return
 	}
 
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -81,14 +156,42 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
-	query = `
+	err = SetStatus(tx, leaderboard, matchID, username, status)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Match %d %s by %s.\n", matchID, status, username)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.MatchStatus{MatchID: matchID, Status: status, Player: username})
+}
+
+// Record records a match between two players on the leaderboard and updates
+// their stats and Elo. recordedBy is the player who reported the result, if
+// known, and can't confirm or dispute it later. Results that can't be
+// recorded are reported as *problem.Problem.
+func Record(tx *sql.Tx, leaderboard *models.Leaderboard, username1, username2, score, recordedBy string) (*models.MatchResult, error) {
+	if username1 == username2 {
+		return nil, problem.New(problem.SamePlayer, "Player can't play against himself.")
+	}
+
+	matchScore, err := ParseScore(score)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
 	SELECT * FROM players
 	WHERE leaderboard_id = $1 AND username = $2
 	`
@@ -105,15 +208,13 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		&player1.CurrentStreak,
 		&player1.Elo,
 		&player1.CreatedAt,
+		&player1.LadderPosition,
 	)
 	if err != nil {
-		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Player %s does not exist on %s leaderboard.\n", username1, name), http.StatusNotFound)
-			return
+			return nil, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username1, leaderboard.Name))
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	player2 := &models.Player{}
@@ -129,15 +230,13 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		&player2.CurrentStreak,
 		&player2.Elo,
 		&player2.CreatedAt,
+		&player2.LadderPosition,
 	)
 	if err != nil {
-		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Player %s does not exist on %s leaderboard.\n", username2, name), http.StatusNotFound)
-			return
+			return nil, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username2, leaderboard.Name))
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	player1.TotalGamesWon += matchScore.P1
@@ -166,6 +265,28 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		player2.CurrentStreak = 0
 	}
 
+	var recorder sql.NullInt64
+	switch recordedBy {
+	case "":
+	case player1.Username:
+		recorder = sql.NullInt64{Int64: int64(player1.ID), Valid: true}
+	case player2.Username:
+		recorder = sql.NullInt64{Int64: int64(player2.ID), Valid: true}
+	default:
+		query = `
+		SELECT id FROM players
+		WHERE leaderboard_id = $1 AND username = $2
+		`
+		err = tx.QueryRow(query, leaderboard.ID, recordedBy).Scan(&recorder.Int64)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", recordedBy, leaderboard.Name))
+			}
+			return nil, err
+		}
+		recorder.Valid = true
+	}
+
 	p1OldElo, p2OldElo := player1.Elo, player2.Elo
 	updateElo(winner, loser, matchScore.P1 == matchScore.P2)
 
@@ -179,7 +300,7 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		total_games_lost = $5,
 		current_streak = $6,
 		elo = $7
-	WHERE id = $8 
+	WHERE id = $8
 	`
 	_, err = tx.Exec(query,
 		player1.MatchesWon,
@@ -192,9 +313,7 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		player1.ID,
 	)
 	if err != nil {
-		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	_, err = tx.Exec(query,
@@ -208,28 +327,15 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		player2.ID,
 	)
 	if err != nil {
-		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	query = `
-	INSERT INTO matches (leaderboard_id, player1_id, player2_id, score)
-	VALUES ($1, $2, $3, $4);
+	INSERT INTO matches (leaderboard_id, player1_id, player2_id, score, recorded_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
 	`
 
-	_, err = tx.Exec(query,
-		leaderboard.ID,
-		player1.ID,
-		player2.ID,
-		score,
-	)
-	if err != nil {
-		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
-		return
-	}
-
 	result := &models.MatchResult{
 		P1:        player1,
 		P2:        player2,
@@ -238,7 +344,23 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		Score:     matchScore,
 	}
 
-	response := fmt.Sprintf("Match recorded: (%+d) %s %d - %d %s (%+d) !\n",
+	err = tx.QueryRow(query,
+		leaderboard.ID,
+		player1.ID,
+		player2.ID,
+		score,
+		recorder,
+	).Scan(&result.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Summary is the message a recorded match is announced with.
+func Summary(result *models.MatchResult) string {
+	return fmt.Sprintf("Match recorded: (%+d) %s %d - %d %s (%+d) !\n",
 		result.P1EloDiff,
 		result.P1.Username,
 		result.Score.P1,
@@ -246,18 +368,15 @@ func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
 		result.P2.Username,
 		result.P2EloDiff,
 	)
+}
 
-	go func() {
-		urls, err := webhooks.All(h.db, name)
-		if err == nil {
-			log.Printf("err: %v\n", err)
-			webhooks.Notify(urls, response)
-		}
-	}()
-
-	log.Print(response)
-
-	w.Write([]byte(response))
+// ParseScore parses a score such as 2-1, the first player's score first.
+func ParseScore(score string) (*models.MatchScore, error) {
+	matchScore, err := parseScore(score)
+	if err != nil {
+		return nil, problem.New(problem.InvalidScore, fmt.Sprintf("Invalid score: %s.", err.Error()))
+	}
+	return matchScore, nil
 }
 
 func parseScore(score string) (*models.MatchScore, error) {
@@ -286,32 +405,3 @@ func parseScore(score string) (*models.MatchScore, error) {
 		P2: p2Score,
 	}, nil
 }
-
-func updateElo(winner, loser *models.Player, isDraw bool) {
-	qW := math.Pow(10, float64(winner.Elo)/400)
-	qL := math.Pow(10, float64(loser.Elo)/400)
-
-	eW := qW / (qW + qL)
-	eL := qL / (qW + qL)
-
-	kFactor := func(rating int) float64 {
-		if rating < 2100 {
-			return 32
-		}
-		if rating >= 2100 && rating < 2400 {
-			return 24
-		}
-		return 16
-	}
-
-	kW := kFactor(winner.Elo)
-	kL := kFactor(loser.Elo)
-
-	sW, sL := 1.0, 0.0
-	if isDraw {
-		sW, sL = 0.5, 0.5
-	}
-
-	winner.Elo = winner.Elo + int(math.Round(kW*(sW-eW)))
-	loser.Elo = loser.Elo + int(math.Round(kL*(sL-eL)))
-}
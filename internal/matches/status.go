@@ -0,0 +1,82 @@
+package matches
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+)
+
+// The statuses of a match. Matches count as soon as they are recorded; their
+// players can then confirm the result or dispute it.
+const (
+	Recorded  = "recorded"
+	Confirmed = "confirmed"
+	Disputed  = "disputed"
+)
+
+// SetStatus confirms or disputes the match on behalf of one of its players,
+// other than the one who recorded it. A match can only be confirmed or
+// disputed once.
+func SetStatus(tx *sql.Tx, leaderboard *models.Leaderboard, matchID int, username, status string) error {
+	query := `
+	SELECT m.status, p1.id, p1.username, p2.id, p2.username, COALESCE(p.username, ''), m.recorded_by
+	FROM matches m
+	JOIN players p1 ON p1.id = m.player1_id
+	JOIN players p2 ON p2.id = m.player2_id
+	LEFT JOIN players p ON p.id = m.status_by
+	WHERE m.id = $1 AND m.leaderboard_id = $2
+	FOR UPDATE OF m
+	`
+
+	var current, statusBy string
+	var recordedBy sql.NullInt64
+	player1, player2 := &models.Player{}, &models.Player{}
+	err := tx.QueryRow(query, matchID, leaderboard.ID).Scan(
+		&current,
+		&player1.ID,
+		&player1.Username,
+		&player2.ID,
+		&player2.Username,
+		&statusBy,
+		&recordedBy,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return problem.New(problem.MatchNotFound, fmt.Sprintf("Match %d does not exist on %s leaderboard.", matchID, leaderboard.Name))
+		}
+		return err
+	}
+
+	verb := "confirm"
+	if status == Disputed {
+		verb = "dispute"
+	}
+
+	var player, opponent *models.Player
+	switch username {
+	case player1.Username:
+		player, opponent = player1, player2
+	case player2.Username:
+		player, opponent = player2, player1
+	default:
+		return problem.New(problem.NotMatchPlayer, fmt.Sprintf("Only %s or %s can %s match %d.", player1.Username, player2.Username, verb, matchID))
+	}
+
+	if recordedBy.Valid && recordedBy.Int64 == int64(player.ID) {
+		return problem.New(problem.NotMatchPlayer, fmt.Sprintf("%s recorded match %d, so only %s can %s it.", player.Username, matchID, opponent.Username, verb))
+	}
+
+	if current != Recorded {
+		return problem.New(problem.MatchAlreadyReviewed, fmt.Sprintf("Match %d was already %s by %s.", matchID, current, statusBy))
+	}
+
+	query = `
+	UPDATE matches
+	SET status = $1, status_by = $2
+	WHERE id = $3
+	`
+	_, err = tx.Exec(query, status, player.ID, matchID)
+	return err
+}
@@ -0,0 +1,134 @@
+package matchmaking
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// activeWithinDays is how recently a player needs to have played to be
+// included in the daily pairings.
+const activeWithinDays = 14
+
+// Daily posts pairings of active players to the webhooks of every leaderboard
+// once a day, at the given offset from midnight. It never returns.
+func Daily(db *sql.DB, at time.Duration) {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(at)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+
+		time.Sleep(time.Until(next))
+
+		if err := postPairings(db); err != nil {
+			log.Printf("err: %v\n", err)
+		}
+	}
+}
+
+func postPairings(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, name FROM leaderboards`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var leaderboards []models.Leaderboard
+	for rows.Next() {
+		l := models.Leaderboard{}
+		if err := rows.Scan(&l.ID, &l.Name); err != nil {
+			return err
+		}
+		leaderboards = append(leaderboards, l)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range leaderboards {
+		if err := pairings(db, &l); err != nil {
+			log.Printf("err: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// pairings queues the day's pairings on the leaderboard for its webhooks.
+func pairings(db *sql.DB, l *models.Leaderboard) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT DISTINCT p.username, p.elo
+	FROM players p
+	JOIN matches m ON m.player1_id = p.id OR m.player2_id = p.id
+	WHERE p.leaderboard_id = $1 AND m.played_at > CURRENT_TIMESTAMP - make_interval(days => $2)
+	`
+
+	rows, err := tx.Query(query, l.ID, activeWithinDays)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var players []*models.Player
+	for rows.Next() {
+		player := &models.Player{}
+		if err = rows.Scan(&player.Username, &player.Elo); err != nil {
+			return err
+		}
+		players = append(players, player)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	history, err := LoadHistory(tx, l.ID)
+	if err != nil {
+		return err
+	}
+
+	pairs := Pair(players, history)
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	e := webhooks.NewEvent(webhook.PairingsSuggested, l.Name, "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Today's suggested matches on leaderboard %s:\n", l.Name)
+	for _, s := range pairs {
+		fmt.Fprintf(&b, "%s (%.0f%%) vs %s (%.0f%%)\n",
+			s.Player.Username,
+			s.WinProbability*100,
+			s.Opponent.Username,
+			(1-s.WinProbability)*100,
+		)
+		e.Pairings = append(e.Pairings, &webhook.Pairing{
+			Player1:        s.Player.Username,
+			Player2:        s.Opponent.Username,
+			WinProbability: s.WinProbability,
+		})
+	}
+	e.Text = b.String()
+
+	return webhooks.Enqueue(tx, l.ID, e)
+}
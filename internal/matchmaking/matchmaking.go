@@ -0,0 +1,146 @@
+package matchmaking
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+
+	"github.com/6ixfigs/pingypongy/internal/matches"
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+const (
+	// staleAfterDays is how long two players have to go without a match for
+	// the rematch to count as fully fresh.
+	staleAfterDays = 30.
+
+	closenessWeight = 0.6
+	recencyWeight   = 0.3
+	newMatchWeight  = 0.1
+)
+
+// History is what is known about previous matches between two players. The
+// zero value describes two players who have never met.
+type History struct {
+	Played              bool
+	DaysSinceLastPlayed float64
+}
+
+// Rank scores every opponent for player and returns the suggestions ordered
+// from the best match to the worst. history is keyed by pairKey, as returned
+// by LoadHistory.
+func Rank(player *models.Player, opponents []*models.Player, history map[string]History) []*models.Suggestion {
+	suggestions := make([]*models.Suggestion, 0, len(opponents))
+	for _, opponent := range opponents {
+		suggestions = append(suggestions, suggest(player, opponent, history[pairKey(player.Username, opponent.Username)]))
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+
+	return suggestions
+}
+
+// Pair greedily matches up players, taking the best scoring pair first, until
+// fewer than two players are left.
+func Pair(players []*models.Player, history map[string]History) []*models.Suggestion {
+	type candidate struct {
+		i, j int
+		s    *models.Suggestion
+	}
+
+	var candidates []candidate
+	for i := range players {
+		for j := i + 1; j < len(players); j++ {
+			h := history[pairKey(players[i].Username, players[j].Username)]
+			candidates = append(candidates, candidate{i, j, suggest(players[i], players[j], h)})
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].s.Score > candidates[b].s.Score
+	})
+
+	paired := make(map[int]bool)
+	var pairs []*models.Suggestion
+	for _, c := range candidates {
+		if paired[c.i] || paired[c.j] {
+			continue
+		}
+		paired[c.i], paired[c.j] = true, true
+		pairs = append(pairs, c.s)
+	}
+
+	return pairs
+}
+
+func suggest(player, opponent *models.Player, h History) *models.Suggestion {
+	p := matches.Predict(player, opponent, 0)
+
+	// 1 for a coin flip, 0 for a certain result.
+	closeness := 1 - 2*math.Abs(p.P1ExpectedScore-0.5)
+
+	recency, newMatch := 1., 1.
+	if h.Played {
+		recency = math.Min(h.DaysSinceLastPlayed/staleAfterDays, 1)
+		newMatch = 0
+	}
+
+	return &models.Suggestion{
+		Player:              player,
+		Opponent:            opponent,
+		WinProbability:      p.P1ExpectedScore,
+		NeverPlayed:         !h.Played,
+		DaysSinceLastPlayed: h.DaysSinceLastPlayed,
+		Score:               closenessWeight*closeness + recencyWeight*recency + newMatchWeight*newMatch,
+	}
+}
+
+func pairKey(username1, username2 string) string {
+	if username1 > username2 {
+		username1, username2 = username2, username1
+	}
+	return username1 + "\x00" + username2
+}
+
+// LoadHistory returns the history of every pair of players that has met on
+// the leaderboard.
+func LoadHistory(tx *sql.Tx, leaderboardID int) (map[string]History, error) {
+	query := `
+	SELECT p1.username, p2.username, EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MAX(m.played_at)) / 86400
+	FROM matches m
+	JOIN players p1 ON p1.id = m.player1_id
+	JOIN players p2 ON p2.id = m.player2_id
+	WHERE m.leaderboard_id = $1
+	GROUP BY p1.username, p2.username
+	`
+
+	rows, err := tx.Query(query, leaderboardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make(map[string]History)
+	for rows.Next() {
+		var username1, username2 string
+		var days float64
+		if err := rows.Scan(&username1, &username2, &days); err != nil {
+			return nil, err
+		}
+
+		// The same pair shows up twice when each of them has been player1.
+		key := pairKey(username1, username2)
+		if h, ok := history[key]; ok && h.DaysSinceLastPlayed < days {
+			continue
+		}
+		history[key] = History{Played: true, DaysSinceLastPlayed: days}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -0,0 +1,142 @@
+package matchmaking
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+func usernames(suggestions []*models.Suggestion) []string {
+	var names []string
+	for _, s := range suggestions {
+		names = append(names, s.Opponent.Username)
+	}
+	return names
+}
+
+func TestRank(t *testing.T) {
+	player := &models.Player{Username: "2pac", Elo: 1000}
+
+	tests := []struct {
+		name      string
+		opponents []*models.Player
+		history   map[string]History
+		want      []string
+	}{
+		{
+			name: "never played first",
+			opponents: []*models.Player{
+				{Username: "biggie", Elo: 1000},
+				{Username: "nas", Elo: 1000},
+				{Username: "eazy-e", Elo: 1000},
+			},
+			history: map[string]History{
+				pairKey("2pac", "biggie"): {Played: true, DaysSinceLastPlayed: 1},
+				pairKey("2pac", "nas"):    {Played: true, DaysSinceLastPlayed: 60},
+			},
+			want: []string{"eazy-e", "nas", "biggie"},
+		},
+		{
+			name: "closest first",
+			opponents: []*models.Player{
+				{Username: "biggie", Elo: 1600},
+				{Username: "nas", Elo: 1000},
+				{Username: "eazy-e", Elo: 850},
+			},
+			want: []string{"nas", "eazy-e", "biggie"},
+		},
+		{
+			name: "a long break makes up for a closer match",
+			opponents: []*models.Player{
+				{Username: "biggie", Elo: 1000},
+				{Username: "nas", Elo: 1050},
+			},
+			history: map[string]History{
+				pairKey("2pac", "biggie"): {Played: true},
+				pairKey("nas", "2pac"):    {Played: true, DaysSinceLastPlayed: 45},
+			},
+			want: []string{"nas", "biggie"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := Rank(player, tt.opponents, tt.history)
+			if got := usernames(suggestions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("suggestions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankScores(t *testing.T) {
+	player := &models.Player{Username: "2pac", Elo: 1000}
+	opponents := []*models.Player{
+		{Username: "biggie", Elo: 1000},
+		{Username: "nas", Elo: 1400},
+	}
+	history := map[string]History{
+		pairKey("2pac", "nas"): {Played: true, DaysSinceLastPlayed: 15},
+	}
+
+	suggestions := Rank(player, opponents, history)
+
+	biggie := suggestions[0]
+	if biggie.WinProbability != 0.5 || !biggie.NeverPlayed || math.Abs(biggie.Score-1) > 1e-9 {
+		t.Errorf("biggie = %+v, want an even, never played match scoring 1", *biggie)
+	}
+
+	nas := suggestions[1]
+	if math.Abs(nas.WinProbability-1./11) > 1e-9 {
+		t.Errorf("win probability against nas = %f, want %f", nas.WinProbability, 1./11)
+	}
+	closeness := 1 - 2*(0.5-1./11)
+	if want := closenessWeight*closeness + recencyWeight*0.5; math.Abs(nas.Score-want) > 1e-9 {
+		t.Errorf("score against nas = %f, want %f", nas.Score, want)
+	}
+	if nas.NeverPlayed || nas.DaysSinceLastPlayed != 15 {
+		t.Errorf("nas = %+v, want played 15 days ago", *nas)
+	}
+}
+
+func TestPair(t *testing.T) {
+	players := []*models.Player{
+		{Username: "2pac", Elo: 1000},
+		{Username: "biggie", Elo: 1400},
+		{Username: "nas", Elo: 1010},
+		{Username: "eazy-e", Elo: 2000},
+		{Username: "snoop", Elo: 1420},
+	}
+
+	pairs := Pair(players, nil)
+
+	var got [][2]string
+	seen := make(map[string]bool)
+	for _, p := range pairs {
+		got = append(got, [2]string{p.Player.Username, p.Opponent.Username})
+		for _, username := range []string{p.Player.Username, p.Opponent.Username} {
+			if seen[username] {
+				t.Errorf("%s is paired twice", username)
+			}
+			seen[username] = true
+		}
+	}
+
+	want := [][2]string{{"2pac", "nas"}, {"biggie", "snoop"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pairs = %v, want %v", got, want)
+	}
+	if seen["eazy-e"] {
+		t.Errorf("eazy-e is paired, want them left out")
+	}
+}
+
+func TestPairTooFewPlayers(t *testing.T) {
+	for _, players := range [][]*models.Player{nil, {{Username: "2pac", Elo: 1000}}} {
+		if pairs := Pair(players, nil); len(pairs) != 0 {
+			t.Errorf("Pair(%d players) = %d pairs, want none", len(players), len(pairs))
+		}
+	}
+}
@@ -1,34 +1,239 @@
 package models
 
 type Leaderboard struct {
-	ID        int
-	Name      string
-	CreatedAt string
+	ID                  int    `json:"id,omitempty"`
+	Name                string `json:"name"`
+	Type                string `json:"type,omitempty"`
+	ChallengeRange      int    `json:"challenge_range,omitempty"`
+	ChallengeExpiryDays int    `json:"challenge_expiry_days,omitempty"`
+	CreatedAt           string `json:"created_at,omitempty"`
 }
 
 type Player struct {
-	ID             int
-	LeaderboardID  int
-	Username       string
-	MatchesWon     int
-	MatchesDrawn   int
-	MatchesLost    int
-	TotalGamesWon  int
-	TotalGamesLost int
-	CurrentStreak  int
-	Elo            int
-	CreatedAt      string
+	ID             int    `json:"-"`
+	LeaderboardID  int    `json:"-"`
+	Username       string `json:"username"`
+	MatchesWon     int    `json:"matches_won"`
+	MatchesDrawn   int    `json:"matches_drawn"`
+	MatchesLost    int    `json:"matches_lost"`
+	TotalGamesWon  int    `json:"total_games_won"`
+	TotalGamesLost int    `json:"total_games_lost"`
+	CurrentStreak  int    `json:"current_streak"`
+	Elo            int    `json:"elo"`
+	CreatedAt      string `json:"created_at,omitempty"`
+	LadderPosition int    `json:"ladder_position,omitempty"`
 }
 
 type MatchScore struct {
-	P1 int
-	P2 int
+	P1 int `json:"player1"`
+	P2 int `json:"player2"`
 }
 
 type MatchResult struct {
-	P1        *Player
-	P2        *Player
-	P1EloDiff int
-	P2EloDiff int
-	Score     *MatchScore
+	ID        int         `json:"id,omitempty"`
+	P1        *Player     `json:"player1"`
+	P2        *Player     `json:"player2"`
+	P1EloDiff int         `json:"player1_elo_diff"`
+	P2EloDiff int         `json:"player2_elo_diff"`
+	Score     *MatchScore `json:"score,omitempty"`
+}
+
+type EloChange struct {
+	Win  int `json:"win"`
+	Draw int `json:"draw"`
+	Loss int `json:"loss"`
+}
+
+type Prediction struct {
+	P1               *Player    `json:"player1"`
+	P2               *Player    `json:"player2"`
+	P1ExpectedScore  float64    `json:"player1_expected_score"`
+	P2ExpectedScore  float64    `json:"player2_expected_score"`
+	P1WinProbability float64    `json:"player1_win_probability"`
+	P2WinProbability float64    `json:"player2_win_probability"`
+	DrawProbability  float64    `json:"draw_probability"`
+	P1EloChange      *EloChange `json:"player1_elo_change"`
+	P2EloChange      *EloChange `json:"player2_elo_change"`
+}
+
+type Suggestion struct {
+	Player              *Player `json:"-"`
+	Opponent            *Player `json:"opponent"`
+	WinProbability      float64 `json:"win_probability"`
+	NeverPlayed         bool    `json:"never_played"`
+	DaysSinceLastPlayed float64 `json:"days_since_last_played"`
+	Score               float64 `json:"score,omitempty"`
+}
+
+type Tournament struct {
+	ID            int                 `json:"id,omitempty"`
+	LeaderboardID int                 `json:"-"`
+	Name          string              `json:"name"`
+	Format        string              `json:"format"`
+	Rounds        int                 `json:"rounds,omitempty"`
+	Players       []*TournamentPlayer `json:"players,omitempty"`
+	Matches       []*TournamentMatch  `json:"matches,omitempty"`
+	Winner        *Player             `json:"winner,omitempty"`
+	CreatedAt     string              `json:"created_at,omitempty"`
+}
+
+type TournamentPlayer struct {
+	Seed   int     `json:"seed"`
+	Player *Player `json:"player"`
+}
+
+type TournamentMatch struct {
+	ID      int         `json:"id,omitempty"`
+	Round   int         `json:"round"`
+	Slot    int         `json:"slot"`
+	P1      *Player     `json:"player1"`
+	P2      *Player     `json:"player2"`
+	Score   *MatchScore `json:"score,omitempty"`
+	Winner  *Player     `json:"winner,omitempty"`
+	MatchID int         `json:"match_id,omitempty"`
+}
+
+type Standing struct {
+	Player    *Player `json:"player"`
+	Seed      int     `json:"seed"`
+	Played    int     `json:"played"`
+	Won       int     `json:"won"`
+	Drawn     int     `json:"drawn"`
+	Lost      int     `json:"lost"`
+	GamesWon  int     `json:"games_won"`
+	GamesLost int     `json:"games_lost"`
+	Points    float64 `json:"points"`
+}
+
+type Challenge struct {
+	ID            int     `json:"id,omitempty"`
+	LeaderboardID int     `json:"-"`
+	Challenger    *Player `json:"challenger"`
+	Challenged    *Player `json:"challenged"`
+	Status        string  `json:"status"`
+	MatchID       int     `json:"match_id,omitempty"`
+	CreatedAt     string  `json:"created_at,omitempty"`
+	AcceptedAt    string  `json:"accepted_at,omitempty"`
+	ExpiresAt     string  `json:"expires_at,omitempty"`
+	ResolvedAt    string  `json:"resolved_at,omitempty"`
+}
+
+type LadderChange struct {
+	Player      *Player `json:"player"`
+	ChallengeID int     `json:"challenge_id"`
+	OldPosition int     `json:"old_position"`
+	NewPosition int     `json:"new_position"`
+	ChangedAt   string  `json:"changed_at"`
+}
+
+type Webhook struct {
+	ID                  int      `json:"id,omitempty"`
+	LeaderboardID       int      `json:"-"`
+	URL                 string   `json:"url"`
+	Secret              string   `json:"-"`
+	Format              string   `json:"format"`
+	Enabled             bool     `json:"enabled"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	Events              []string `json:"events"`
+	CreatedAt           string   `json:"created_at,omitempty"`
+	DisabledAt          string   `json:"disabled_at,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID          int    `json:"id,omitempty"`
+	WebhookID   int    `json:"webhook_id,omitempty"`
+	Event       string `json:"event"`
+	Attempt     int    `json:"attempt"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	LatencyMs   int    `json:"latency_ms"`
+	Error       string `json:"error,omitempty"`
+	Response    string `json:"response,omitempty"`
+	AttemptedAt string `json:"attempted_at"`
+}
+
+type MessageTemplate struct {
+	ID            int    `json:"id,omitempty"`
+	LeaderboardID int    `json:"-"`
+	WebhookID     int    `json:"webhook_id,omitempty"`
+	Event         string `json:"event"`
+	Body          string `json:"body"`
+	UpdatedAt     string `json:"updated_at,omitempty"`
+}
+
+type Identity struct {
+	ID            int    `json:"id,omitempty"`
+	LeaderboardID int    `json:"-"`
+	PlayerID      int    `json:"-"`
+	Provider      string `json:"provider"`
+	ExternalID    string `json:"external_id"`
+	CreatedAt     string `json:"created_at,omitempty"`
+}
+
+// Standings are the players of a leaderboard, best first.
+type Standings struct {
+	Leaderboard *Leaderboard `json:"leaderboard"`
+	Players     []Player     `json:"players"`
+}
+
+// Suggestions are the opponents suggested for a player, best first.
+type Suggestions struct {
+	Player      *Player       `json:"player"`
+	Suggestions []*Suggestion `json:"suggestions"`
+}
+
+// MatchStatus is a match that was just confirmed or disputed.
+type MatchStatus struct {
+	MatchID int    `json:"match_id"`
+	Status  string `json:"status"`
+	Player  string `json:"player"`
+}
+
+// TournamentView is a tournament along with its standings, which only
+// round-robin and Swiss tournaments have.
+type TournamentView struct {
+	*Tournament
+	Standings []*Standing `json:"standings,omitempty"`
+}
+
+type TournamentSummary struct {
+	Name    string `json:"name"`
+	Format  string `json:"format"`
+	Players int    `json:"players"`
+	Winner  string `json:"winner,omitempty"`
+}
+
+// TournamentResult is a tournament match that was just recorded, along with
+// where it left the tournament.
+type TournamentResult struct {
+	Match      *MatchResult    `json:"match"`
+	Tournament *TournamentView `json:"tournament"`
+}
+
+// ChallengeResult is a challenge that was just settled by its match.
+type ChallengeResult struct {
+	Match     *MatchResult `json:"match"`
+	Challenge *Challenge   `json:"challenge"`
+}
+
+// WebhookSecret is a webhook along with its signing secret, which is only
+// shown when the webhook is registered or the secret rotated.
+type WebhookSecret struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveries is a webhook along with its latest delivery attempts.
+type WebhookDeliveries struct {
+	Webhook    *Webhook           `json:"webhook"`
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+// DeletedWebhooks tells how many webhooks were deleted.
+type DeletedWebhooks struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// TemplatePreview is a template rendered against sample data.
+type TemplatePreview struct {
+	Text string `json:"text"`
 }
@@ -6,8 +6,12 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/6ixfigs/pingypongy/internal/matchmaking"
 	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
 	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/lib/pq"
@@ -28,12 +32,16 @@ func NewHandler(db *sql.DB) *Handler {
 func (h *Handler) MountRoutes() {
 	h.Rtr.Post("/", h.Create)
 	h.Rtr.Get("/{username}", h.Stats)
+	h.Rtr.Get("/{username}/suggest", h.Suggest)
+	h.Rtr.Post("/{username}/identities", h.Link)
+	h.Rtr.Get("/{username}/identities", h.Identities)
+	h.Rtr.Delete("/{username}/identities/{provider}", h.Unlink)
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
 		return
 	}
 
@@ -43,7 +51,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -54,9 +62,12 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
+	// The leaderboard is locked so that players created at the same time
+	// don't join the ladder at the same position.
 	query := `
 	SELECT id, name FROM leaderboards
 	WHERE name = $1
+	FOR UPDATE
 	`
 
 	l := &models.Leaderboard{}
@@ -67,45 +78,53 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
+	// New players join at the bottom of the ladder.
 	query = `
-	INSERT INTO players (leaderboard_id, username)
-	VALUES ($1, $2)
+	INSERT INTO players (leaderboard_id, username, ladder_position)
+	SELECT $1, $2, COALESCE(MAX(ladder_position), 0) + 1
+	FROM players
+	WHERE leaderboard_id = $1
+	RETURNING elo, ladder_position
 	`
 
-	_, err = tx.Exec(query, l.ID, username)
+	player := &models.Player{Username: username}
+	err = tx.QueryRow(query, l.ID, username).Scan(&player.Elo, &player.LadderPosition)
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err, ok := err.(*pq.Error); ok {
 			if err.Code.Name() == "unique_violation" {
-				http.Error(w, fmt.Sprintf("Player %s already exists on %s leaderboard.", username, name), http.StatusConflict)
+				render.Error(w, r, problem.New(problem.PlayerExists, fmt.Sprintf("Player %s already exists on %s leaderboard.", username, name)))
 				return
 			}
-			http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+			render.Error(w, r, err)
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
 	response := fmt.Sprintf("Created player on leaderboard %s: %s\n", name, username)
 
-	go func() {
-		urls, err := webhooks.All(h.db, name)
-		if err == nil {
-			webhooks.Notify(urls, response)
-		}
-	}()
+	e := webhooks.NewEvent(webhook.PlayerCreated, l.Name, response)
+	e.Players = []*webhook.Player{webhooks.Player(player)}
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
 
 	log.Print(response)
 
-	w.Write([]byte(response))
+	render.Respond(w, r, response, player)
 }
 
 func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
@@ -115,7 +134,7 @@ func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -139,10 +158,10 @@ func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
@@ -163,14 +182,15 @@ func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 		&player.CurrentStreak,
 		&player.Elo,
 		&player.CreatedAt,
+		&player.LadderPosition,
 	)
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Player %s does not exist on %s leaderboard.\n", username, name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
@@ -198,14 +218,130 @@ func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 
 	response := fmt.Sprintf("%s's Stats:\n```\n%s\n```\n", player.Username, t.Render())
 
-	go func() {
-		urls, err := webhooks.All(h.db, name)
-		if err == nil {
-			webhooks.Notify(urls, response)
+	e := webhooks.NewEvent(webhook.StatsViewed, l.Name, response)
+	e.Players = []*webhook.Player{webhooks.Player(player)}
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, player)
+}
+
+func (h *Handler) Suggest(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	username := chi.URLParam(r, "username")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
 		}
 	}()
 
-	log.Print(response)
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	query = `
+	SELECT username, elo FROM players
+	WHERE leaderboard_id = $1
+	`
+
+	rows, err := tx.Query(query, l.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	var player *models.Player
+	var opponents []*models.Player
+	for rows.Next() {
+		p := &models.Player{}
+		err = rows.Scan(
+			&p.Username,
+			&p.Elo,
+		)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+
+		if p.Username == username {
+			player = p
+		} else {
+			opponents = append(opponents, p)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	if player == nil {
+		render.Error(w, r, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, name)))
+		return
+	}
+
+	history, err := matchmaking.LoadHistory(tx, l.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	suggestions := matchmaking.Rank(player, opponents, history)
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"#", "opponent", "Elo", "Win %", "Last Played"})
+	for rank, s := range suggestions {
+		lastPlayed := "never"
+		if !s.NeverPlayed {
+			lastPlayed = fmt.Sprintf("%d days ago", int(s.DaysSinceLastPlayed))
+		}
+
+		t.AppendRow(table.Row{
+			rank + 1,
+			s.Opponent.Username,
+			s.Opponent.Elo,
+			fmt.Sprintf("%.2f%%", s.WinProbability*100),
+			lastPlayed,
+		})
+	}
+
+	response := fmt.Sprintf("Suggested opponents for %s:\n```\n%s\n```\n", player.Username, t.Render())
 
-	w.Write([]byte(response))
+	render.Respond(w, r, response, &models.Suggestions{Player: player, Suggestions: suggestions})
 }
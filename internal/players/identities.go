@@ -0,0 +1,291 @@
+package players
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/go-chi/chi/v5"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lib/pq"
+)
+
+// The providers of the external accounts players can be linked to.
+const (
+	Slack   = "slack"
+	Discord = "discord"
+	Email   = "email"
+)
+
+var providers = []string{Slack, Discord, Email}
+
+// Resolve looks up the player an external account is linked to on the
+// leaderboard. It returns an empty username if the account isn't linked.
+func Resolve(db *sql.DB, leaderboard, provider, externalID string) (string, error) {
+	query := `
+	SELECT p.username
+	FROM identities i
+	JOIN players p ON p.id = i.player_id
+	JOIN leaderboards l ON l.id = i.leaderboard_id
+	WHERE l.name = $1 AND i.provider = $2 AND i.external_id = $3
+	`
+
+	var username string
+	err := db.QueryRow(query, leaderboard, provider, normalize(provider, externalID)).Scan(&username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return username, nil
+}
+
+// normalize puts the id of an external account in the form it is stored in,
+// so that the same account isn't linked twice.
+func normalize(provider, externalID string) string {
+	externalID = strings.TrimSpace(externalID)
+	switch provider {
+	case Slack:
+		return strings.ToUpper(externalID)
+	case Email:
+		return strings.ToLower(externalID)
+	default:
+		return externalID
+	}
+}
+
+func validateIdentity(provider, externalID string) error {
+	if !slices.Contains(providers, provider) {
+		return problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid provider: expected one of: %s.", strings.Join(providers, ", ")))
+	}
+	if externalID == "" {
+		return problem.New(problem.InvalidParameter, "Invalid id: must not be empty.")
+	}
+	if provider == Email && !strings.Contains(externalID, "@") {
+		return problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid id: %s is not an email address.", externalID))
+	}
+	return nil
+}
+
+// Link links an external account to the player. A player has at most one
+// account with each provider, so linking another one replaces it.
+func (h *Handler) Link(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	username := chi.URLParam(r, "username")
+	provider := r.FormValue("provider")
+	externalID := normalize(provider, r.FormValue("id"))
+
+	if err := validateIdentity(provider, externalID); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	player, err := find(tx, name, username)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	INSERT INTO identities (leaderboard_id, player_id, provider, external_id)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (player_id, provider)
+	DO UPDATE SET external_id = EXCLUDED.external_id, created_at = CURRENT_TIMESTAMP
+	`
+	_, err = tx.Exec(query, player.LeaderboardID, player.ID, provider, externalID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			render.Error(w, r, problem.New(problem.IdentityTaken, fmt.Sprintf("%s account %s is already linked to another player on %s leaderboard.", provider, externalID, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Linked %s account %s to player %s on leaderboard %s\n", provider, externalID, username, name)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.Identity{Provider: provider, ExternalID: externalID})
+}
+
+func (h *Handler) Identities(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	username := chi.URLParam(r, "username")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	player, err := find(tx, name, username)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	SELECT provider, external_id, to_char(created_at, 'YYYY-MM-DD HH24:MI')
+	FROM identities
+	WHERE player_id = $1
+	ORDER BY provider
+	`
+
+	rows, err := tx.Query(query, player.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"provider", "id", "linked"})
+	identities := []*models.Identity{}
+	for rows.Next() {
+		identity := &models.Identity{}
+		if err = rows.Scan(&identity.Provider, &identity.ExternalID, &identity.CreatedAt); err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+
+		t.AppendRow(table.Row{identity.Provider, identity.ExternalID, identity.CreatedAt})
+		identities = append(identities, identity)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	var response string
+	if len(identities) > 0 {
+		response = fmt.Sprintf("Accounts linked to %s:\n```\n%s\n```\n", username, t.Render())
+	} else {
+		response = fmt.Sprintf("No accounts linked to %s.\n", username)
+	}
+
+	render.Respond(w, r, response, identities)
+}
+
+func (h *Handler) Unlink(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	username := chi.URLParam(r, "username")
+	provider := chi.URLParam(r, "provider")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	player, err := find(tx, name, username)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	DELETE FROM identities
+	WHERE player_id = $1 AND provider = $2
+	RETURNING external_id
+	`
+
+	var externalID string
+	err = tx.QueryRow(query, player.ID, provider).Scan(&externalID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.IdentityNotFound, fmt.Sprintf("No %s account is linked to %s.", provider, username)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Unlinked %s account %s from player %s on leaderboard %s\n", provider, externalID, username, name)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.Identity{Provider: provider, ExternalID: externalID})
+}
+
+// find looks up the player on the leaderboard. Players and leaderboards
+// that don't exist are reported as *problem.Problem.
+func find(tx *sql.Tx, name, username string) (*models.Player, error) {
+	query := `
+	SELECT p.id, p.leaderboard_id, p.username
+	FROM leaderboards l
+	LEFT JOIN players p ON p.leaderboard_id = l.id AND p.username = $2
+	WHERE l.name = $1
+	`
+
+	var id, leaderboardID sql.NullInt64
+	var playerName sql.NullString
+	err := tx.QueryRow(query, name, username).Scan(&id, &leaderboardID, &playerName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name))
+		}
+		return nil, err
+	}
+	if !id.Valid {
+		return nil, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, name))
+	}
+
+	return &models.Player{
+		ID:            int(id.Int64),
+		LeaderboardID: int(leaderboardID.Int64),
+		Username:      playerName.String,
+	}, nil
+}
@@ -0,0 +1,194 @@
+// Package render lets handlers answer scripts in JSON while people keep
+// getting text.
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxBodySize is the largest JSON request body accepted.
+const maxBodySize = 1 << 20
+
+// JSON reports whether the client asked for a JSON response. Text is the
+// default, for the CLI and chats.
+func JSON(r *http.Request) bool {
+	return accepts(r, "application/json")
+}
+
+// accepts reports whether the client's Accept header lists one of the media
+// types.
+func accepts(r *http.Request, mediaTypes ...string) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaType))
+			if err == nil && slices.Contains(mediaTypes, mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Respond writes v as JSON if the client asked for it, or else the text.
+func Respond(w http.ResponseWriter, r *http.Request, text string, v any) {
+	RespondStatus(w, r, http.StatusOK, text, v)
+}
+
+// RespondStatus is Respond with a status other than 200 OK.
+func RespondStatus(w http.ResponseWriter, r *http.Request, status int, text string, v any) {
+	if !JSON(r) {
+		w.WriteHeader(status)
+		w.Write([]byte(text))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("err: %v\n", err)
+	}
+}
+
+// Error responds with err, as problem details if the client accepts JSON or
+// else as text. Errors other than a *problem.Problem are internal errors; the
+// caller logs them, and the client is only told something went wrong.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	p := problem.New(problem.InternalError, "Something went wrong.")
+	var known *problem.Problem
+	if errors.As(err, &known) {
+		// The problem is copied, as errors can be shared between requests.
+		*p = *known
+	}
+	p.Instance = r.URL.Path
+	p.RequestID = middleware.GetReqID(r.Context())
+
+	if !accepts(r, "application/json", problem.ContentType) {
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", problem.ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Printf("err: %v\n", err)
+	}
+}
+
+// NotFound responds to requests for routes that don't exist.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, problem.New(problem.NotFound, fmt.Sprintf("%s does not exist.", r.URL.Path)))
+}
+
+// MethodNotAllowed responds to requests for routes that exist, but not with
+// the request's method.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, problem.New(problem.MethodNotAllowed, fmt.Sprintf("%s does not support %s.", r.URL.Path, r.Method)))
+}
+
+// Form lets handlers read JSON request bodies the same way as forms. The
+// fields of a JSON object become form values, with arrays giving a field
+// several values. Bodies that are neither JSON nor a form are rejected.
+func Form(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "application/x-www-form-urlencoded":
+			next.ServeHTTP(w, r)
+			return
+		case "application/json":
+		default:
+			Error(w, r, problem.New(problem.UnsupportedMediaType, "Request bodies have to be application/x-www-form-urlencoded or application/json."))
+			return
+		}
+
+		d := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+		d.UseNumber()
+
+		fields := map[string]any{}
+		if err := d.Decode(&fields); err != nil && err != io.EOF {
+			log.Printf("err: %v\n", err)
+			Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+			return
+		}
+
+		postForm := url.Values{}
+		for name, field := range fields {
+			values, err := formValues(field)
+			if err != nil {
+				Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid %s: %s.", name, err.Error())))
+				return
+			}
+			postForm[name] = values
+		}
+
+		// Like ParseForm, body values come before query values.
+		form := url.Values{}
+		for name, values := range postForm {
+			form[name] = append(form[name], values...)
+		}
+		for name, values := range r.URL.Query() {
+			form[name] = append(form[name], values...)
+		}
+
+		r.PostForm = postForm
+		r.Form = form
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func formValues(field any) ([]string, error) {
+	switch v := field.(type) {
+	case nil:
+		return []string{}, nil
+	case []any:
+		values := []string{}
+		for _, item := range v {
+			value, err := formValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		value, err := formValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+}
+
+func formValue(field any) (string, error) {
+	switch v := field.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		return "", fmt.Errorf("expected a string, number or boolean")
+	}
+}
@@ -0,0 +1,17 @@
+package rest
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// spec is the OpenAPI document describing the versioned API. Routes added to
+// the API have to be described in it too, or the tests fail.
+//
+//go:embed openapi.json
+var spec []byte
+
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
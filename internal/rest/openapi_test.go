@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+func testServer(t *testing.T) *Server {
+	guard, err := webhooks.NewGuard(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{Rtr: chi.NewRouter(), guard: guard}
+}
+
+// specRoutes lists the routes described in the OpenAPI document, as
+// "METHOD /path".
+func specRoutes(t *testing.T) map[string]bool {
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Servers []struct{ URL string }                `json:"servers"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("openapi.json is invalid: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", doc.OpenAPI)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/api/v1" {
+		t.Errorf("servers = %v, want /api/v1", doc.Servers)
+	}
+
+	routes := map[string]bool{}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			routes[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return routes
+}
+
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	described := specRoutes(t)
+
+	mounted := map[string]bool{}
+	err := chi.Walk(testServer(t).api(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// Routes on the root of a mounted router end with a slash, which
+		// is redirected away.
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		mounted[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range mounted {
+		if !described[route] {
+			t.Errorf("%s is not described in openapi.json", route)
+		}
+	}
+	for route := range described {
+		if !mounted[route] {
+			t.Errorf("%s is described in openapi.json but not mounted", route)
+		}
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	s := testServer(t)
+	s.Cfg = &config.Config{}
+	s.MountRoutes()
+
+	for _, path := range []string{"/api/v1/openapi.json", "/openapi.json"} {
+		w := httptest.NewRecorder()
+		s.Rtr.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want %d", path, w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("GET %s Content-Type = %q, want application/json", path, got)
+		}
+	}
+}
@@ -4,21 +4,30 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/6ixfigs/pingypongy/internal/config"
 	"github.com/6ixfigs/pingypongy/internal/db"
+	"github.com/6ixfigs/pingypongy/internal/discord"
+	"github.com/6ixfigs/pingypongy/internal/ladder"
 	"github.com/6ixfigs/pingypongy/internal/leaderboards"
 	"github.com/6ixfigs/pingypongy/internal/matches"
+	"github.com/6ixfigs/pingypongy/internal/matchmaking"
 	"github.com/6ixfigs/pingypongy/internal/players"
+	"github.com/6ixfigs/pingypongy/internal/render"
+	"github.com/6ixfigs/pingypongy/internal/slack"
+	"github.com/6ixfigs/pingypongy/internal/tournaments"
 	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type Server struct {
-	Rtr *chi.Mux
-	Cfg *config.Config
-	db  *sql.DB
+	Rtr   *chi.Mux
+	Cfg   *config.Config
+	db    *sql.DB
+	guard *webhooks.Guard
 }
 
 func NewServer() (*Server, error) {
@@ -32,25 +41,64 @@ func NewServer() (*Server, error) {
 		return nil, err
 	}
 
+	guard, err := webhooks.NewGuard(cfg.WebhookAllowlist)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Server{
-		Rtr: chi.NewRouter(),
-		Cfg: cfg,
-		db:  db,
+		Rtr:   chi.NewRouter(),
+		Cfg:   cfg,
+		db:    db,
+		guard: guard,
 	}, nil
 }
 
 func (s *Server) MountRoutes() {
+	s.Rtr.Use(middleware.RequestID)
 	s.Rtr.Use(requestLogger)
 	s.Rtr.Use(middleware.Recoverer)
 	s.Rtr.Use(middleware.CleanPath)
 	s.Rtr.Use(middleware.RedirectSlashes)
-	s.Rtr.Use(middleware.AllowContentType("application/x-www-form-urlencoded"))
 	s.Rtr.Use(middleware.Heartbeat("/ping"))
 
+	s.Rtr.NotFound(render.NotFound)
+	s.Rtr.MethodNotAllowed(render.MethodNotAllowed)
+
+	api := s.api()
+	s.Rtr.Mount("/api/v1", api)
+
+	// The paths from before the API was versioned are kept as aliases.
+	s.Rtr.Mount("/", api)
+
+	if s.Cfg.SlackSigningSecret != "" {
+		sh := slack.NewHandler(s.db, s.Rtr, s.Cfg)
+		sh.MountRoutes()
+		s.Rtr.Mount("/slack", sh.Rtr)
+	}
+
+	// Discord's JSON isn't a form, so it is mounted outside of the API, which
+	// reads JSON bodies as forms.
+	if s.Cfg.DiscordPublicKey != "" {
+		dh := discord.NewHandler(s.db, s.Rtr, s.Cfg)
+		dh.MountRoutes()
+		s.Rtr.Mount("/discord", dh.Rtr)
+	}
+}
+
+// api is the versioned API, described by the OpenAPI document it serves.
+func (s *Server) api() chi.Router {
+	r := chi.NewRouter()
+	r.Use(render.Form)
+	r.NotFound(render.NotFound)
+	r.MethodNotAllowed(render.MethodNotAllowed)
+
+	r.Get("/openapi.json", serveSpec)
+
 	lh := leaderboards.NewHandler(s.db)
 	lh.MountRoutes()
 
-	wh := webhooks.NewHandler(s.db)
+	wh := webhooks.NewHandler(s.db, s.guard)
 	wh.MountRoutes()
 
 	ph := players.NewHandler(s.db)
@@ -59,15 +107,60 @@ func (s *Server) MountRoutes() {
 	mh := matches.NewHandler(s.db)
 	mh.MountRoutes()
 
-	s.Rtr.Mount("/leaderboards", lh.Rtr)
-	s.Rtr.Mount("/leaderboards/{leaderboard_name}/webhooks", wh.Rtr)
-	s.Rtr.Mount("/leaderboards/{leaderboard_name}/players", ph.Rtr)
-	s.Rtr.Mount("/leaderboards/{leaderboard_name}/matches", mh.Rtr)
+	th := tournaments.NewHandler(s.db)
+	th.MountRoutes()
+
+	ldh := ladder.NewHandler(s.db)
+	ldh.MountRoutes()
+
+	r.Mount("/leaderboards", lh.Rtr)
+	r.Mount("/leaderboards/{leaderboard_name}/webhooks", wh.Rtr)
+	r.Mount("/leaderboards/{leaderboard_name}/players", ph.Rtr)
+	r.Mount("/leaderboards/{leaderboard_name}/matches", mh.Rtr)
+	r.Mount("/leaderboards/{leaderboard_name}/tournaments", th.Rtr)
+	r.Mount("/leaderboards/{leaderboard_name}/ladder", ldh.Rtr)
+
+	return r
+}
+
+// ScheduleDailyPairings starts posting suggested matches to webhooks once a
+// day if it is enabled in the config.
+func (s *Server) ScheduleDailyPairings() error {
+	if s.Cfg.DailyPairingsAt == "" {
+		return nil
+	}
+
+	at, err := time.Parse("15:04", s.Cfg.DailyPairingsAt)
+	if err != nil {
+		return err
+	}
+
+	go matchmaking.Daily(s.db, time.Duration(at.Hour())*time.Hour+time.Duration(at.Minute())*time.Minute)
+
+	log.Printf("daily pairings scheduled at %s", s.Cfg.DailyPairingsAt)
+	return nil
+}
+
+// ScheduleLadderSweep starts forfeiting expired ladder challenges in the
+// background. Reading a ladder doesn't forfeit them, so that reads never
+// lock it; only challenging, accepting and playing do.
+func (s *Server) ScheduleLadderSweep() {
+	go ladder.Sweep(s.db, time.Hour)
+}
+
+// StartDispatcher starts delivering the queued webhook notifications in the
+// background.
+func (s *Server) StartDispatcher() {
+	go webhooks.Dispatch(s.db, s.guard)
 }
 
+// requestLogger logs requests along with their id, which is sent back in a
+// header so that clients can point at the request when reporting problems.
 func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("HTTP %s %s", r.Method, r.URL.Path)
+		id := middleware.GetReqID(r.Context())
+		w.Header().Set(problem.RequestIDHeader, id)
+		log.Printf("HTTP %s %s [%s]", r.Method, r.URL.Path, id)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,118 @@
+package slack
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/chat"
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/6ixfigs/pingypongy/internal/players"
+	"github.com/go-chi/chi/v5"
+)
+
+type Handler struct {
+	Rtr           chi.Router
+	api           http.Handler
+	client        *http.Client
+	signingSecret string
+	botToken      string
+	leaderboard   string
+
+	// linked finds the player a Slack user's account is linked to on a
+	// leaderboard.
+	linked func(leaderboard, userID string) (string, error)
+}
+
+// NewHandler creates a handler for Slack's requests. Commands are run
+// against api, which should be the REST API's router.
+func NewHandler(db *sql.DB, api http.Handler, cfg *config.Config) *Handler {
+	return &Handler{
+		Rtr:           chi.NewRouter(),
+		api:           api,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		signingSecret: cfg.SlackSigningSecret,
+		botToken:      cfg.BotToken,
+		leaderboard:   cfg.SlackLeaderboard,
+		linked: func(leaderboard, userID string) (string, error) {
+			return players.Resolve(db, leaderboard, players.Slack, userID)
+		},
+	}
+}
+
+func (h *Handler) MountRoutes() {
+	h.Rtr.Post("/commands", h.Commands)
+	h.Rtr.Post("/interactions", h.Interactions)
+}
+
+// message is a reply to a slash command.
+type message struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Commands handles the /pong slash command.
+func (h *Handler) Commands(w http.ResponseWriter, r *http.Request) {
+	if err := verify(h.signingSecret, r); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid signature.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		return
+	}
+
+	userID := r.FormValue("user_id")
+	args := strings.Fields(r.FormValue("text"))
+
+	// Players can leave themselves out of their own commands.
+	switch {
+	case len(args) == 1 && args[0] == "stats":
+		args = append(args, "me")
+	case len(args) == 3 && args[0] == "record":
+		args = []string{"record", "me", args[1], args[2]}
+	}
+
+	// Only a linked account proves who ran the command, so that nobody can
+	// confirm or dispute matches on behalf of someone else.
+	invoker, err := h.linked(h.leaderboard, userID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		reply(w, &chat.Reply{Text: "Couldn't find out who you are.\n"})
+		return
+	}
+
+	for i := 1; i < len(args); i++ {
+		username, err := h.player(r.Context(), h.leaderboard, userID, args[i])
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			reply(w, &chat.Reply{Text: fmt.Sprintf("Couldn't find out who %s is.\n", args[i])})
+			return
+		}
+		args[i] = username
+	}
+
+	reply(w, chat.Run(h.api, h.leaderboard, invoker, args))
+}
+
+// escape escapes the characters Slack treats as markup in messages.
+var escape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func reply(w http.ResponseWriter, rep *chat.Reply) {
+	m := &message{ResponseType: "ephemeral", Text: escape.Replace(rep.Text)}
+	if rep.Public {
+		m.ResponseType = "in_channel"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m); err != nil {
+		log.Printf("err: %v\n", err)
+	}
+}
@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeAPI stands in for the REST API, recording matches between anyone but
+// players called nobody, and confirming any match.
+func fakeAPI(t *testing.T) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/api/v1/leaderboards/{name}/matches/{id}/confirm", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Match " + chi.URLParam(r, "id") + " confirmed by " + r.FormValue("player") + ".\n"))
+	})
+	r.Post("/api/v1/leaderboards/{name}/matches", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "name") != "OnlyRealGs" {
+			t.Errorf("leaderboard = %s, want OnlyRealGs", chi.URLParam(r, "name"))
+		}
+		p1, p2 := r.FormValue("player1"), r.FormValue("player2")
+		if p1 == "nobody" || p2 == "nobody" {
+			http.Error(w, "Player nobody does not exist.", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("Match recorded: " + p1 + " " + r.FormValue("score") + " " + p2 + " by " + r.FormValue("recorded_by") + "\n"))
+	})
+	return r
+}
+
+// testHandler returns a handler whose only linked account is U024BE7LH,
+// linked to 2pac.
+func testHandler(t *testing.T) *Handler {
+	h := NewHandler(nil, fakeAPI(t), &config.Config{
+		SlackSigningSecret: testSecret,
+		SlackLeaderboard:   "OnlyRealGs",
+	})
+	h.linked = func(leaderboard, userID string) (string, error) {
+		if userID == "U024BE7LH" {
+			return "2pac", nil
+		}
+		return "", nil
+	}
+	return h
+}
+
+// command runs /pong with the text as the Slack user.
+func command(t *testing.T, h *Handler, userID, text string) *message {
+	body := url.Values{"command": {"/pong"}, "text": {text}, "user_id": {userID}}.Encode()
+	w := httptest.NewRecorder()
+	h.Commands(w, signedRequest(testSecret, time.Now().Unix(), body))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	m := &message{}
+	if err := json.Unmarshal(w.Body.Bytes(), m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCommandsRecord(t *testing.T) {
+	h := testHandler(t)
+
+	tests := []struct {
+		name     string
+		text     string
+		wantType string
+		wantText string
+	}{
+		{
+			name:     "recorded",
+			text:     "record 2pac eazy-e 2-1",
+			wantType: "in_channel",
+			wantText: "Match recorded: 2pac 2-1 eazy-e by 2pac\n",
+		},
+		{
+			name:     "failed",
+			text:     "record 2pac nobody 2-1",
+			wantType: "ephemeral",
+			wantText: "Player nobody does not exist.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := command(t, h, "U024BE7LH", tt.text)
+			if m.ResponseType != tt.wantType {
+				t.Errorf("response_type = %s, want %s", m.ResponseType, tt.wantType)
+			}
+			if m.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", m.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCommandsConfirm(t *testing.T) {
+	h := testHandler(t)
+
+	tests := []struct {
+		name     string
+		userID   string
+		text     string
+		wantType string
+		wantText string
+	}{
+		{
+			name:     "linked",
+			userID:   "U024BE7LH",
+			text:     "confirm 7",
+			wantType: "in_channel",
+			wantText: "Match 7 confirmed by 2pac.\n",
+		},
+		{
+			name:     "not linked",
+			userID:   "U0G9QF9C6",
+			text:     "confirm 7",
+			wantType: "ephemeral",
+			wantText: "Link your account to your player to confirm matches.\n",
+		},
+		{
+			name:     "on behalf of someone else",
+			userID:   "U0G9QF9C6",
+			text:     "confirm 7 2pac",
+			wantType: "ephemeral",
+			wantText: "Usage: `confirm &lt;match-id&gt;`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := command(t, h, tt.userID, tt.text)
+			if m.ResponseType != tt.wantType {
+				t.Errorf("response_type = %s, want %s", m.ResponseType, tt.wantType)
+			}
+			if m.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", m.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCommandsRejectsUnsigned(t *testing.T) {
+	h := testHandler(t)
+
+	body := url.Values{"text": {"record 2pac eazy-e 2-1"}}.Encode()
+	r := signedRequest("not the secret", time.Now().Unix(), body)
+	w := httptest.NewRecorder()
+	h.Commands(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,316 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/6ixfigs/pingypongy/internal/chat"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+)
+
+// rematchCallback identifies the rematch modal when it is submitted.
+const rematchCallback = "rematch"
+
+// interaction is the payload Slack sends when someone clicks a button or
+// submits a modal.
+type interaction struct {
+	Type        string `json:"type"`
+	TriggerID   string `json:"trigger_id"`
+	ResponseURL string `json:"response_url"`
+	User        struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Message struct {
+		Text   string            `json:"text"`
+		Blocks []json.RawMessage `json:"blocks"`
+	} `json:"message"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	View struct {
+		CallbackID      string `json:"callback_id"`
+		PrivateMetadata string `json:"private_metadata"`
+		State           struct {
+			Values map[string]map[string]struct {
+				Value string `json:"value"`
+			} `json:"values"`
+		} `json:"state"`
+	} `json:"view"`
+}
+
+// response is a message sent to the response_url of an interaction.
+type response struct {
+	ResponseType    string `json:"response_type,omitempty"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	Text            string `json:"text"`
+	Blocks          []any  `json:"blocks,omitempty"`
+}
+
+// Interactions handles the buttons on match messages and the rematch modal.
+func (h *Handler) Interactions(w http.ResponseWriter, r *http.Request) {
+	if err := verify(h.signingSecret, r); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid signature.", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		return
+	}
+
+	i := &interaction{}
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), i); err != nil {
+		log.Printf("err: %v\n", err)
+		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case i.Type == "block_actions" && len(i.Actions) > 0:
+		// Slack expects an answer within 3 seconds, and the outcome goes to
+		// the response_url anyway, so the action is handled after the ack.
+		go h.blockAction(context.WithoutCancel(r.Context()), i)
+	case i.Type == "view_submission" && i.View.CallbackID == rematchCallback:
+		h.rematch(w, i)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// blockAction handles a click on one of the buttons of a match message.
+// Confirming or disputing the match replaces the buttons with its new
+// status, and failures are shown only to whoever clicked.
+func (h *Handler) blockAction(ctx context.Context, i *interaction) {
+	action := i.Actions[0]
+
+	m := &webhooks.MatchAction{}
+	if err := json.Unmarshal([]byte(action.Value), m); err != nil {
+		log.Printf("err: %v\n", err)
+		return
+	}
+
+	var command string
+	switch action.ActionID {
+	case webhooks.ActionConfirm:
+		command = "confirm"
+	case webhooks.ActionDispute:
+		command = "dispute"
+	case webhooks.ActionRematch:
+		if err := h.openRematch(ctx, i.TriggerID, m); err != nil {
+			log.Printf("err: %v\n", err)
+			h.respond(ctx, i.ResponseURL, &response{ResponseType: "ephemeral", Text: "Couldn't open the rematch form."})
+		}
+		return
+	default:
+		return
+	}
+
+	username, err := h.linked(m.Leaderboard, i.User.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		h.respond(ctx, i.ResponseURL, &response{ResponseType: "ephemeral", Text: "Couldn't find out who you are."})
+		return
+	}
+
+	rep := chat.Run(h.api, m.Leaderboard, username, []string{command, strconv.Itoa(m.Match)})
+	if !rep.Public {
+		h.respond(ctx, i.ResponseURL, &response{ResponseType: "ephemeral", Text: escape.Replace(rep.Text)})
+		return
+	}
+
+	status := "✅ Confirmed by " + username
+	if command == "dispute" {
+		status = "⚠️ Disputed by " + username
+	}
+
+	// The buttons are dropped, so that the match isn't confirmed or
+	// disputed twice.
+	var blocks []any
+	for _, b := range i.Message.Blocks {
+		var block struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(b, &block) == nil && block.Type == "actions" {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	blocks = append(blocks, map[string]any{
+		"type":     "context",
+		"elements": []any{map[string]string{"type": "mrkdwn", "text": escape.Replace(status)}},
+	})
+
+	h.respond(ctx, i.ResponseURL, &response{ReplaceOriginal: true, Text: i.Message.Text, Blocks: blocks})
+}
+
+// respond sends a message to the response_url of an interaction.
+func (h *Handler) respond(ctx context.Context, responseURL string, resp *response) {
+	u, err := url.Parse(responseURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() != "hooks.slack.com" {
+		log.Printf("err: invalid response_url %q\n", responseURL)
+		return
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		log.Printf("err: response_url returned %s\n", res.Status)
+	}
+}
+
+type viewText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type viewInput struct {
+	Type    string         `json:"type"`
+	BlockID string         `json:"block_id"`
+	Label   *viewText      `json:"label"`
+	Element *viewTextInput `json:"element"`
+}
+
+type viewTextInput struct {
+	Type         string    `json:"type"`
+	ActionID     string    `json:"action_id"`
+	InitialValue string    `json:"initial_value,omitempty"`
+	Placeholder  *viewText `json:"placeholder,omitempty"`
+}
+
+type view struct {
+	Type            string       `json:"type"`
+	CallbackID      string       `json:"callback_id"`
+	PrivateMetadata string       `json:"private_metadata"`
+	Title           *viewText    `json:"title"`
+	Submit          *viewText    `json:"submit"`
+	Close           *viewText    `json:"close"`
+	Blocks          []*viewInput `json:"blocks"`
+}
+
+// openRematch opens a modal to record a new match between the same players,
+// with the players filled in.
+func (h *Handler) openRematch(ctx context.Context, triggerID string, m *webhooks.MatchAction) error {
+	input := func(id, label, value, placeholder string) *viewInput {
+		in := &viewInput{
+			Type:    "input",
+			BlockID: id,
+			Label:   &viewText{Type: "plain_text", Text: label},
+			Element: &viewTextInput{Type: "plain_text_input", ActionID: id, InitialValue: value},
+		}
+		if placeholder != "" {
+			in.Element.Placeholder = &viewText{Type: "plain_text", Text: placeholder}
+		}
+		return in
+	}
+
+	v := &view{
+		Type:            "modal",
+		CallbackID:      rematchCallback,
+		PrivateMetadata: m.Leaderboard,
+		Title:           &viewText{Type: "plain_text", Text: "Rematch"},
+		Submit:          &viewText{Type: "plain_text", Text: "Record"},
+		Close:           &viewText{Type: "plain_text", Text: "Cancel"},
+		Blocks: []*viewInput{
+			input("player1", "Player 1", m.Player1, ""),
+			input("player2", "Player 2", m.Player2, ""),
+			input("score", "Score", "", "2-1"),
+		},
+	}
+
+	return h.call(ctx, "views.open", map[string]any{"trigger_id": triggerID, "view": v})
+}
+
+// rematch records the match submitted through the rematch modal. Problems
+// are shown next to the score, and the modal is closed once it's recorded.
+func (h *Handler) rematch(w http.ResponseWriter, i *interaction) {
+	value := func(id string) string {
+		return i.View.State.Values[id][id].Value
+	}
+
+	recordedBy, err := h.linked(i.View.PrivateMetadata, i.User.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+	}
+
+	rep := chat.Run(h.api, i.View.PrivateMetadata, recordedBy, []string{"record", value("player1"), value("player2"), value("score")})
+
+	w.Header().Set("Content-Type", "application/json")
+	if rep.Public {
+		w.Write([]byte("{}"))
+		return
+	}
+
+	body := map[string]any{
+		"response_action": "errors",
+		"errors":          map[string]string{"score": rep.Text},
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("err: %v\n", err)
+	}
+}
+
+// call calls a method of the Slack Web API with the bot token.
+func (h *Handler) call(ctx context.Context, method string, args any) error {
+	if h.botToken == "" {
+		return fmt.Errorf("no bot token to call %s", method)
+	}
+
+	body, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+h.botToken)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := &struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("%s: %s", method, result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The headers Slack signs its requests with.
+const (
+	TimestampHeader = "X-Slack-Request-Timestamp"
+	SignatureHeader = "X-Slack-Signature"
+)
+
+const (
+	// tolerance is how old a request can be before it is rejected as a
+	// possible replay.
+	tolerance = 5 * time.Minute
+
+	// maxBodySize is the largest request Slack is expected to send.
+	maxBodySize = 1 << 20
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrTooOld           = errors.New("timestamp too old")
+)
+
+// Sign computes the signature Slack sends in the X-Slack-Signature header of
+// a request with the given timestamp and body. It can be used to send signed
+// requests to a local pongo in place of Slack.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%d:", timestamp)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks that the request was signed by Slack with the signing
+// secret. It reads the body and puts it back, so that the form can still be
+// parsed afterwards.
+func verify(secret string, r *http.Request) error {
+	ts, signature := r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader)
+	if ts == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrTooOld
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
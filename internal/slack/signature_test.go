@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// signedRequest returns a request signed with the secret the way Slack signs
+// them.
+func signedRequest(secret string, timestamp int64, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	r.Header.Set(SignatureHeader, Sign(secret, timestamp, []byte(body)))
+	return r
+}
+
+func TestVerify(t *testing.T) {
+	body := "command=%2Fpong&text=board&user_id=U024BE7LH"
+	now := time.Now().Unix()
+
+	r := signedRequest(testSecret, now, body)
+	if err := verify(testSecret, r); err != nil {
+		t.Fatalf("verify() = %v, want nil", err)
+	}
+	if err := r.ParseForm(); err != nil || r.FormValue("text") != "board" {
+		t.Errorf("form after verify = %v, %v, want the body to be readable again", r.Form, err)
+	}
+
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want error
+	}{
+		{
+			name: "wrong secret",
+			req:  func() *http.Request { return signedRequest("not the secret", now, body) },
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "tampered body",
+			req: func() *http.Request {
+				r := signedRequest(testSecret, now, body)
+				r.Body = io.NopCloser(strings.NewReader(body + "&text=stats"))
+				return r
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "stale timestamp",
+			req: func() *http.Request {
+				return signedRequest(testSecret, time.Now().Add(-tolerance-time.Minute).Unix(), body)
+			},
+			want: ErrTooOld,
+		},
+		{
+			name: "timestamp in the future",
+			req: func() *http.Request {
+				return signedRequest(testSecret, time.Now().Add(tolerance+time.Minute).Unix(), body)
+			},
+			want: ErrTooOld,
+		},
+		{
+			name: "missing signature",
+			req: func() *http.Request {
+				r := signedRequest(testSecret, now, body)
+				r.Header.Del(SignatureHeader)
+				return r
+			},
+			want: ErrMissingSignature,
+		},
+		{
+			name: "missing timestamp",
+			req: func() *http.Request {
+				r := signedRequest(testSecret, now, body)
+				r.Header.Del(TimestampHeader)
+				return r
+			},
+			want: ErrMissingSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(testSecret, tt.req()); !errors.Is(err, tt.want) {
+				t.Errorf("verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
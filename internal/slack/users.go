@@ -0,0 +1,101 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// apiURL is the base URL of the Slack Web API.
+var apiURL = "https://slack.com/api"
+
+// mention matches a user mentioned in a command, such as <@U024BE7LH> or
+// <@U024BE7LH|bob>.
+var mention = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|([^>]*))?>$`)
+
+type usersInfoResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	User  struct {
+		Name    string `json:"name"`
+		Profile struct {
+			DisplayName string `json:"display_name"`
+		} `json:"profile"`
+	} `json:"user"`
+}
+
+// username finds the player a Slack user plays as on the leaderboard: the
+// one their account is linked to, or else the one named after them in Slack.
+func (h *Handler) username(ctx context.Context, leaderboard, userID string) (string, error) {
+	username, err := h.linked(leaderboard, userID)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		return username, nil
+	}
+
+	return h.displayName(ctx, userID)
+}
+
+// displayName looks up the Slack user with the bot token and returns their
+// display name, or their username if they haven't set one.
+func (h *Handler) displayName(ctx context.Context, userID string) (string, error) {
+	if h.botToken == "" {
+		return "", fmt.Errorf("no bot token to look up user %s", userID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL+"/users.info?user="+url.QueryEscape(userID), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.botToken)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	info := &usersInfoResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return "", err
+	}
+	if !info.OK {
+		return "", fmt.Errorf("users.info %s: %s", userID, info.Error)
+	}
+
+	if info.User.Profile.DisplayName != "" {
+		return info.User.Profile.DisplayName, nil
+	}
+	return info.User.Name, nil
+}
+
+// player resolves an argument naming a player. "me" is whoever ran the
+// command and mentions are looked up in Slack; anything else is taken to be
+// a username already.
+func (h *Handler) player(ctx context.Context, leaderboard, userID, arg string) (string, error) {
+	if arg == "me" {
+		return h.username(ctx, leaderboard, userID)
+	}
+
+	m := mention.FindStringSubmatch(arg)
+	if m == nil {
+		return arg, nil
+	}
+
+	username, err := h.username(ctx, leaderboard, m[1])
+	if err != nil && m[2] != "" {
+		// Older workspaces still include the username in mentions, which
+		// is good enough if the lookup fails.
+		return m[2], nil
+	}
+	return username, err
+}
@@ -0,0 +1,121 @@
+package tournaments
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// seed orders the players by Elo, best first, and numbers them from 1.
+// Players with the same Elo are seeded by username.
+func seed(players []*models.Player) []*models.TournamentPlayer {
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Elo != players[j].Elo {
+			return players[i].Elo > players[j].Elo
+		}
+		return players[i].Username < players[j].Username
+	})
+
+	seeded := make([]*models.TournamentPlayer, 0, len(players))
+	for i, p := range players {
+		seeded = append(seeded, &models.TournamentPlayer{Seed: i + 1, Player: p})
+	}
+	return seeded
+}
+
+// newBracket lays out a single-elimination bracket for players, who must be
+// ordered by seed. The bracket is padded to the next power of two and the top
+// seeds get a bye through the first round.
+func newBracket(players []*models.TournamentPlayer) (int, []*models.TournamentMatch) {
+	rounds, size := 1, 2
+	for size < len(players) {
+		rounds++
+		size *= 2
+	}
+
+	seeded := func(seed int) *models.Player {
+		if seed > len(players) {
+			return nil
+		}
+		return players[seed-1].Player
+	}
+
+	var bracket []*models.TournamentMatch
+	order := seedOrder(size)
+	for slot := 0; slot < size/2; slot++ {
+		bracket = append(bracket, &models.TournamentMatch{
+			Round: 1,
+			Slot:  slot,
+			P1:    seeded(order[2*slot]),
+			P2:    seeded(order[2*slot+1]),
+		})
+	}
+
+	for round, slots := 2, size/4; round <= rounds; round, slots = round+1, slots/2 {
+		for slot := 0; slot < slots; slot++ {
+			bracket = append(bracket, &models.TournamentMatch{
+				Round: round,
+				Slot:  slot,
+			})
+		}
+	}
+
+	for _, m := range bracket[:size/2] {
+		if m.P2 == nil {
+			m.Winner = m.P1
+			advance(bracket, m)
+		}
+	}
+
+	return rounds, bracket
+}
+
+// seedOrder returns the seeds of a bracket of the given size in the order they
+// are placed, so that the top two seeds can only meet in the final.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// advance moves the winner of m into its match in the next round and returns
+// that match, or nil if m was the final.
+func advance(bracket []*models.TournamentMatch, m *models.TournamentMatch) *models.TournamentMatch {
+	for _, next := range bracket {
+		if next.Round != m.Round+1 || next.Slot != m.Slot/2 {
+			continue
+		}
+
+		if m.Slot%2 == 0 {
+			next.P1 = m.Winner
+		} else {
+			next.P2 = m.Winner
+		}
+		return next
+	}
+	return nil
+}
+
+func roundName(t *models.Tournament, round int) string {
+	if t.Format != SingleElimination {
+		return fmt.Sprintf("Round %d", round)
+	}
+
+	switch t.Rounds - round {
+	case 0:
+		return "Final"
+	case 1:
+		return "Semifinals"
+	case 2:
+		return "Quarterfinals"
+	default:
+		return fmt.Sprintf("Round %d", round)
+	}
+}
@@ -0,0 +1,172 @@
+package tournaments
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// testPlayers returns n players seeded by their IDs, 1 being the top seed.
+func testPlayers(n int) []*models.TournamentPlayer {
+	var players []*models.TournamentPlayer
+	for i := 1; i <= n; i++ {
+		players = append(players, &models.TournamentPlayer{
+			Seed:   i,
+			Player: &models.Player{ID: i, Username: fmt.Sprintf("p%d", i)},
+		})
+	}
+	return players
+}
+
+// pairings describes the matches of a round by the IDs of their players,
+// e.g. "1-8", "1-" for a bye or "-" for a match that's still undecided.
+func pairings(matches []*models.TournamentMatch, round int) []string {
+	id := func(p *models.Player) string {
+		if p == nil {
+			return ""
+		}
+		return fmt.Sprint(p.ID)
+	}
+
+	var paired []string
+	for _, m := range matches {
+		if m.Round == round {
+			paired = append(paired, id(m.P1)+"-"+id(m.P2))
+		}
+	}
+	return paired
+}
+
+func TestSeed(t *testing.T) {
+	players := []*models.Player{
+		{ID: 1, Username: "eazy-e", Elo: 1000},
+		{ID: 2, Username: "2pac", Elo: 1100},
+		{ID: 3, Username: "biggie", Elo: 1000},
+		{ID: 4, Username: "nas", Elo: 1200},
+	}
+
+	var got []string
+	for i, p := range seed(players) {
+		if p.Seed != i+1 {
+			t.Errorf("seed of %s = %d, want %d", p.Player.Username, p.Seed, i+1)
+		}
+		got = append(got, p.Player.Username)
+	}
+
+	want := []string{"nas", "2pac", "biggie", "eazy-e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("seeds = %v, want %v", got, want)
+	}
+}
+
+func TestNewBracket(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+		want    map[int][]string
+	}{
+		{
+			players: 2,
+			rounds:  1,
+			want: map[int][]string{
+				1: {"1-2"},
+			},
+		},
+		{
+			players: 3,
+			rounds:  2,
+			want: map[int][]string{
+				1: {"1-", "2-3"},
+				2: {"1-"},
+			},
+		},
+		{
+			players: 5,
+			rounds:  3,
+			want: map[int][]string{
+				1: {"1-", "4-5", "2-", "3-"},
+				2: {"1-", "2-3"},
+				3: {"-"},
+			},
+		},
+		{
+			players: 8,
+			rounds:  3,
+			want: map[int][]string{
+				1: {"1-8", "4-5", "2-7", "3-6"},
+				2: {"-", "-"},
+				3: {"-"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			rounds, bracket := newBracket(testPlayers(tt.players))
+			if rounds != tt.rounds {
+				t.Errorf("rounds = %d, want %d", rounds, tt.rounds)
+			}
+
+			for round := 1; round <= tt.rounds; round++ {
+				if got := pairings(bracket, round); !reflect.DeepEqual(got, tt.want[round]) {
+					t.Errorf("round %d = %v, want %v", round, got, tt.want[round])
+				}
+			}
+
+			for _, m := range bracket {
+				if m.Round != 1 {
+					continue
+				}
+				if bye := m.P2 == nil; bye != (m.Winner == m.P1) {
+					t.Errorf("slot %d: winner = %v, want it to be set only for a bye", m.Slot, m.Winner)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	_, bracket := newBracket(testPlayers(8))
+
+	play := func(round, slot, winner int) *models.TournamentMatch {
+		t.Helper()
+		for _, m := range bracket {
+			if m.Round != round || m.Slot != slot {
+				continue
+			}
+			if m.P1 != nil && m.P1.ID == winner {
+				m.Winner = m.P1
+			} else {
+				m.Winner = m.P2
+			}
+			return advance(bracket, m)
+		}
+		t.Fatalf("no match in round %d, slot %d", round, slot)
+		return nil
+	}
+
+	if next := play(1, 0, 8); next.Round != 2 || next.Slot != 0 {
+		t.Errorf("1-8 advances to round %d, slot %d, want round 2, slot 0", next.Round, next.Slot)
+	}
+	play(1, 1, 4)
+	play(1, 2, 2)
+	if next := play(1, 3, 6); next.Round != 2 || next.Slot != 1 {
+		t.Errorf("3-6 advances to round %d, slot %d, want round 2, slot 1", next.Round, next.Slot)
+	}
+
+	if got, want := pairings(bracket, 2), []string{"8-4", "2-6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("round 2 = %v, want %v", got, want)
+	}
+
+	play(2, 0, 4)
+	play(2, 1, 2)
+	if got, want := pairings(bracket, 3), []string{"4-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("final = %v, want %v", got, want)
+	}
+
+	if next := play(3, 0, 2); next != nil {
+		t.Errorf("final advances to round %d, slot %d, want nowhere", next.Round, next.Slot)
+	}
+}
@@ -0,0 +1,674 @@
+package tournaments
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/internal/matches"
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
+	"github.com/6ixfigs/pingypongy/internal/webhooks"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+	"github.com/go-chi/chi/v5"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lib/pq"
+)
+
+const (
+	SingleElimination = "single-elimination"
+	RoundRobin        = "round-robin"
+	Swiss             = "swiss"
+)
+
+type Handler struct {
+	Rtr chi.Router
+	db  *sql.DB
+}
+
+// newTournamentView adds the standings to a tournament, which only
+// round-robin and Swiss tournaments have.
+func newTournamentView(t *models.Tournament) *models.TournamentView {
+	v := &models.TournamentView{Tournament: t}
+	if t.Format != SingleElimination {
+		v.Standings = standings(t)
+	}
+	return v
+}
+
+func NewHandler(db *sql.DB) *Handler {
+	return &Handler{
+		Rtr: chi.NewRouter(),
+		db:  db,
+	}
+}
+
+func (h *Handler) MountRoutes() {
+	h.Rtr.Post("/", h.Create)
+	h.Rtr.Get("/", h.List)
+	h.Rtr.Get("/{tournament_name}", h.Show)
+	h.Rtr.Post("/{tournament_name}/matches", h.Record)
+}
+
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	tournamentName := r.FormValue("name")
+	if strings.TrimSpace(tournamentName) == "" {
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid name: must not be empty."))
+		return
+	}
+
+	format := r.FormValue("format")
+	switch format {
+	case "":
+		format = SingleElimination
+	case SingleElimination, RoundRobin, Swiss:
+	default:
+		render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid format: %s. Use %s, %s or %s.", format, SingleElimination, RoundRobin, Swiss)))
+		return
+	}
+
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, value := range r.Form["players"] {
+		for _, username := range strings.Split(value, ",") {
+			username = strings.TrimSpace(username)
+			if username == "" {
+				continue
+			}
+			if seen[username] {
+				render.Error(w, r, problem.New(problem.DuplicatePlayer, fmt.Sprintf("Player %s is listed more than once.", username)))
+				return
+			}
+			seen[username] = true
+			usernames = append(usernames, username)
+		}
+	}
+
+	if len(usernames) < 2 {
+		render.Error(w, r, problem.New(problem.NotEnoughPlayers, "A tournament needs at least two players."))
+		return
+	}
+
+	rounds := swissRounds(len(usernames))
+	if r.FormValue("rounds") != "" {
+		if format != Swiss {
+			render.Error(w, r, problem.New(problem.InvalidParameter, "Only Swiss tournaments have a configurable number of rounds."))
+			return
+		}
+
+		n, err := strconv.Atoi(r.FormValue("rounds"))
+		if err != nil || n < 1 || n >= len(usernames) {
+			render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid rounds: must be between 1 and %d.", len(usernames)-1)))
+			return
+		}
+		rounds = n
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	query = `
+	SELECT id, username, elo FROM players
+	WHERE leaderboard_id = $1 AND username = ANY($2)
+	`
+
+	rows, err := tx.Query(query, l.ID, pq.Array(usernames))
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	var players []*models.Player
+	for rows.Next() {
+		p := &models.Player{}
+		err = rows.Scan(
+			&p.ID,
+			&p.Username,
+			&p.Elo,
+		)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+		players = append(players, p)
+		delete(seen, p.Username)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	for _, username := range usernames {
+		if seen[username] {
+			render.Error(w, r, problem.New(problem.PlayerNotFound, fmt.Sprintf("Player %s does not exist on %s leaderboard.", username, name)))
+			return
+		}
+	}
+
+	t := &models.Tournament{
+		LeaderboardID: l.ID,
+		Name:          tournamentName,
+		Format:        format,
+		Players:       seed(players),
+	}
+
+	switch format {
+	case SingleElimination:
+		t.Rounds, t.Matches = newBracket(t.Players)
+	case RoundRobin:
+		t.Rounds, t.Matches = newRoundRobin(t.Players)
+	case Swiss:
+		t.Rounds, t.Matches = newSwiss(t.Players, rounds)
+	}
+
+	err = insert(tx, t)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			render.Error(w, r, problem.New(problem.TournamentExists, fmt.Sprintf("Tournament %s already exists on %s leaderboard.", tournamentName, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Created tournament on leaderboard %s: %s\n%s", name, tournamentName, describe(t))
+
+	log.Print(response)
+
+	render.Respond(w, r, response, newTournamentView(t))
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	query = `
+	SELECT t.name, t.format, COUNT(tp.player_id), p.username
+	FROM tournaments t
+	JOIN tournament_players tp ON tp.tournament_id = t.id
+	LEFT JOIN players p ON p.id = t.winner_id
+	WHERE t.leaderboard_id = $1
+	GROUP BY t.id, p.username
+	ORDER BY t.created_at DESC
+	`
+
+	rows, err := tx.Query(query, l.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"tournament", "format", "players", "winner"})
+	listed := []*models.TournamentSummary{}
+	for rows.Next() {
+		var tournamentName, format string
+		var players int
+		var winner sql.NullString
+		err = rows.Scan(&tournamentName, &format, &players, &winner)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+
+		listed = append(listed, &models.TournamentSummary{Name: tournamentName, Format: format, Players: players, Winner: winner.String})
+
+		if !winner.Valid {
+			winner.String = "in progress"
+		}
+		t.AppendRow(table.Row{tournamentName, format, players, winner.String})
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	var response string
+	if len(listed) > 0 {
+		response = fmt.Sprintf("Tournaments on leaderboard %s:\n```\n%s\n```\n", l.Name, t.Render())
+	} else {
+		response = "No tournaments created.\n"
+	}
+
+	render.Respond(w, r, response, listed)
+}
+
+func (h *Handler) Show(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	tournamentName := chi.URLParam(r, "tournament_name")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	t, err := load(tx, l.ID, tournamentName)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.TournamentNotFound, fmt.Sprintf("Tournament %s does not exist on %s leaderboard.", tournamentName, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Tournament %s on leaderboard %s:\n%s", t.Name, l.Name, describe(t))
+
+	render.Respond(w, r, response, newTournamentView(t))
+}
+
+func (h *Handler) Record(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	tournamentName := chi.URLParam(r, "tournament_name")
+	username1 := r.FormValue("player1")
+	username2 := r.FormValue("player2")
+	score := r.FormValue("score")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT id, name FROM leaderboards
+	WHERE name = $1
+	`
+
+	l := &models.Leaderboard{}
+	err = tx.QueryRow(query, name).Scan(
+		&l.ID,
+		&l.Name,
+	)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	t, err := load(tx, l.ID, tournamentName)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err == sql.ErrNoRows {
+			render.Error(w, r, problem.New(problem.TournamentNotFound, fmt.Sprintf("Tournament %s does not exist on %s leaderboard.", tournamentName, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	result, response, err := record(tx, l, t, username1, username2, score)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	e := webhooks.NewEvent(webhook.TournamentMatchRecorded, l.Name, response)
+	e.Match = webhooks.Match(result)
+	e.Tournament = &webhook.Tournament{Name: t.Name, Format: t.Format}
+	if t.Winner != nil {
+		e.Tournament.Winner = t.Winner.Username
+	}
+
+	err = webhooks.Enqueue(tx, l.ID, e)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.TournamentResult{Match: result, Tournament: newTournamentView(t)})
+}
+
+// record records the result of an open tournament match through the normal
+// match flow and moves the tournament on. It returns the recorded match and
+// the message announcing it, followed by the round results once the round is
+// complete.
+func record(tx *sql.Tx, l *models.Leaderboard, t *models.Tournament, username1, username2, score string) (*models.MatchResult, string, error) {
+	if t.Winner != nil {
+		return nil, "", problem.New(problem.TournamentFinished, fmt.Sprintf("Tournament %s is already finished.", t.Name))
+	}
+
+	m := openMatch(t, username1, username2)
+	if m == nil {
+		return nil, "", problem.New(problem.NoOpenMatch, fmt.Sprintf("There is no open match between %s and %s in tournament %s.", username1, username2, t.Name))
+	}
+
+	if t.Format == SingleElimination {
+		s, err := matches.ParseScore(score)
+		if err != nil {
+			return nil, "", err
+		}
+		if s.P1 == s.P2 {
+			return nil, "", problem.New(problem.DrawNotAllowed, "Knockout matches can't end in a draw.")
+		}
+	}
+
+	result, err := matches.Record(tx, l, username1, username2, score, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.Score = result.Score
+	if m.P1.ID != result.P1.ID {
+		m.Score = &models.MatchScore{P1: result.Score.P2, P2: result.Score.P1}
+	}
+	m.MatchID = result.ID
+
+	switch {
+	case m.Score.P1 > m.Score.P2:
+		m.Winner = m.P1
+	case m.Score.P2 > m.Score.P1:
+		m.Winner = m.P2
+	}
+
+	if err := updateMatch(tx, m); err != nil {
+		return nil, "", err
+	}
+
+	if t.Format == SingleElimination {
+		if next := advance(t.Matches, m); next != nil {
+			if err := updateMatch(tx, next); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	response := matches.Summary(result)
+	if !roundComplete(t, m.Round) {
+		return result, response, nil
+	}
+
+	var next []*models.TournamentMatch
+	switch t.Format {
+	case SingleElimination:
+		if m.Round == t.Rounds {
+			t.Winner = m.Winner
+		}
+	case RoundRobin:
+		if finished(t) {
+			t.Winner = standings(t)[0].Player
+		}
+	case Swiss:
+		if m.Round == t.Rounds {
+			t.Winner = standings(t)[0].Player
+		} else {
+			next = nextSwissRound(t, m.Round+1)
+			if err := insertMatches(tx, t, next); err != nil {
+				return nil, "", err
+			}
+			t.Matches = append(t.Matches, next...)
+		}
+	}
+
+	if t.Winner != nil {
+		if err := updateWinner(tx, t); err != nil {
+			return nil, "", err
+		}
+	}
+
+	response += roundResults(t, m.Round)
+	if len(next) > 0 {
+		response += fmt.Sprintf("%s pairings:\n", roundName(t, m.Round+1))
+		for _, m := range next {
+			if isBye(m) {
+				response += fmt.Sprintf("%s has a bye\n", m.P1.Username)
+			} else {
+				response += fmt.Sprintf("%s vs %s\n", m.P1.Username, m.P2.Username)
+			}
+		}
+	}
+
+	return result, response, nil
+}
+
+func openMatch(t *models.Tournament, username1, username2 string) *models.TournamentMatch {
+	for _, m := range t.Matches {
+		if played(m) || m.P1 == nil || m.P2 == nil {
+			continue
+		}
+		if (m.P1.Username == username1 && m.P2.Username == username2) ||
+			(m.P1.Username == username2 && m.P2.Username == username1) {
+			return m
+		}
+	}
+	return nil
+}
+
+func roundComplete(t *models.Tournament, round int) bool {
+	for _, m := range t.Matches {
+		if m.Round == round && !played(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func finished(t *models.Tournament) bool {
+	for _, m := range t.Matches {
+		if !played(m) {
+			return false
+		}
+	}
+	return true
+}
+
+func played(m *models.TournamentMatch) bool {
+	return m.Score != nil || isBye(m)
+}
+
+// isBye reports whether m is a free pass into the next round, or a free point
+// in a Swiss tournament.
+func isBye(m *models.TournamentMatch) bool {
+	return m.Score == nil && m.Winner != nil && m.P2 == nil
+}
+
+func roundResults(t *models.Tournament, round int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tournament %s, %s results:\n", t.Name, roundName(t, round))
+	for _, m := range t.Matches {
+		if m.Round != round || m.Score == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d - %d %s\n", m.P1.Username, m.Score.P1, m.Score.P2, m.P2.Username)
+	}
+
+	if t.Winner != nil {
+		fmt.Fprintf(&b, "%s wins tournament %s!\n", t.Winner.Username, t.Name)
+	}
+
+	return b.String()
+}
+
+func describe(t *models.Tournament) string {
+	seeds := make(map[int]int)
+	for _, p := range t.Players {
+		seeds[p.Player.ID] = p.Seed
+	}
+
+	player := func(m *models.TournamentMatch, p *models.Player) (string, string) {
+		switch {
+		case p != nil:
+			return fmt.Sprint(seeds[p.ID]), p.Username
+		case isBye(m):
+			return "", "bye"
+		default:
+			return "", "TBD"
+		}
+	}
+
+	tw := table.NewWriter()
+	tw.AppendHeader(table.Row{"round", "#", "player 1", "score", "player 2", "#"})
+	for _, m := range t.Matches {
+		seed1, username1 := player(m, m.P1)
+		seed2, username2 := player(m, m.P2)
+
+		score := ""
+		if m.Score != nil {
+			score = fmt.Sprintf("%d - %d", m.Score.P1, m.Score.P2)
+		}
+
+		tw.AppendRow(table.Row{roundName(t, m.Round), seed1, username1, score, username2, seed2})
+	}
+
+	response := fmt.Sprintf("```\n%s\n```\n", tw.Render())
+
+	if t.Format != SingleElimination {
+		st := table.NewWriter()
+		st.AppendHeader(table.Row{"#", "player", "P", "W", "D", "L", "GD", "Pts"})
+		for rank, s := range standings(t) {
+			st.AppendRow(table.Row{
+				rank + 1,
+				s.Player.Username,
+				s.Played,
+				s.Won,
+				s.Drawn,
+				s.Lost,
+				fmt.Sprintf("%+d", s.GamesWon-s.GamesLost),
+				s.Points,
+			})
+		}
+		response += fmt.Sprintf("Standings:\n```\n%s\n```\n", st.Render())
+	}
+
+	if t.Winner != nil {
+		response += fmt.Sprintf("Winner: %s\n", t.Winner.Username)
+	}
+
+	return response
+}
@@ -0,0 +1,44 @@
+package tournaments
+
+import (
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// newRoundRobin schedules every player against every other player once using
+// the circle method. With an odd number of players a different player sits
+// out each round.
+func newRoundRobin(players []*models.TournamentPlayer) (int, []*models.TournamentMatch) {
+	circle := make([]*models.Player, 0, len(players)+1)
+	for _, p := range players {
+		circle = append(circle, p.Player)
+	}
+	if len(circle)%2 == 1 {
+		circle = append(circle, nil)
+	}
+
+	n := len(circle)
+	var schedule []*models.TournamentMatch
+	for round := 1; round < n; round++ {
+		slot := 0
+		for i := 0; i < n/2; i++ {
+			p1, p2 := circle[i], circle[n-1-i]
+			if p1 == nil || p2 == nil {
+				continue
+			}
+
+			schedule = append(schedule, &models.TournamentMatch{
+				Round: round,
+				Slot:  slot,
+				P1:    p1,
+				P2:    p2,
+			})
+			slot++
+		}
+
+		// The first player stays put while everyone else moves one place
+		// around the circle.
+		circle = append([]*models.Player{circle[0], circle[n-1]}, circle[1:n-1]...)
+	}
+
+	return n - 1, schedule
+}
@@ -0,0 +1,73 @@
+package tournaments
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewRoundRobin(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+	}{
+		{players: 2, rounds: 1},
+		{players: 3, rounds: 3},
+		{players: 4, rounds: 3},
+		{players: 5, rounds: 5},
+		{players: 6, rounds: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			rounds, schedule := newRoundRobin(testPlayers(tt.players))
+			if rounds != tt.rounds {
+				t.Errorf("rounds = %d, want %d", rounds, tt.rounds)
+			}
+
+			if want := tt.players * (tt.players - 1) / 2; len(schedule) != want {
+				t.Errorf("%d matches, want %d", len(schedule), want)
+			}
+
+			met := make(map[[2]int]bool)
+			played := make(map[int]int)
+			for round := 1; round <= rounds; round++ {
+				inRound := make(map[int]bool)
+				slot := 0
+				for _, m := range schedule {
+					if m.Round != round {
+						continue
+					}
+					if m.Slot != slot {
+						t.Errorf("round %d: slot = %d, want %d", round, m.Slot, slot)
+					}
+					slot++
+
+					for _, id := range []int{m.P1.ID, m.P2.ID} {
+						if inRound[id] {
+							t.Errorf("round %d: player %d plays twice", round, id)
+						}
+						inRound[id] = true
+						played[id]++
+					}
+
+					pair := [2]int{min(m.P1.ID, m.P2.ID), max(m.P1.ID, m.P2.ID)}
+					if met[pair] {
+						t.Errorf("round %d: %d and %d meet again", round, pair[0], pair[1])
+					}
+					met[pair] = true
+				}
+
+				// With an odd number of players, exactly one sits out.
+				if sittingOut := tt.players - len(inRound); sittingOut != tt.players%2 {
+					t.Errorf("round %d: %d players sit out, want %d", round, sittingOut, tt.players%2)
+				}
+			}
+
+			for id := 1; id <= tt.players; id++ {
+				if played[id] != tt.players-1 {
+					t.Errorf("player %d plays %d matches, want %d", id, played[id], tt.players-1)
+				}
+			}
+		})
+	}
+}
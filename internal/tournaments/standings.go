@@ -0,0 +1,113 @@
+package tournaments
+
+import (
+	"sort"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// standings tallies the tournament matches played so far. A win is worth a
+// point, a draw half a point and so is a bye. Players level on points are
+// separated by the points they took off each other, then by game difference,
+// then by games won and finally by seed.
+func standings(t *models.Tournament) []*models.Standing {
+	table := make([]*models.Standing, 0, len(t.Players))
+	byID := make(map[int]*models.Standing)
+	for _, p := range t.Players {
+		s := &models.Standing{Player: p.Player, Seed: p.Seed}
+		table = append(table, s)
+		byID[p.Player.ID] = s
+	}
+
+	for _, m := range t.Matches {
+		if m.Score == nil {
+			if isBye(m) {
+				byID[m.Winner.ID].Points++
+			}
+			continue
+		}
+
+		s1, s2 := byID[m.P1.ID], byID[m.P2.ID]
+		s1.Played++
+		s2.Played++
+		s1.GamesWon += m.Score.P1
+		s1.GamesLost += m.Score.P2
+		s2.GamesWon += m.Score.P2
+		s2.GamesLost += m.Score.P1
+
+		switch {
+		case m.Score.P1 > m.Score.P2:
+			s1.Won++
+			s1.Points++
+			s2.Lost++
+		case m.Score.P2 > m.Score.P1:
+			s2.Won++
+			s2.Points++
+			s1.Lost++
+		default:
+			s1.Drawn++
+			s1.Points += 0.5
+			s2.Drawn++
+			s2.Points += 0.5
+		}
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].Points > table[j].Points
+	})
+
+	for start := 0; start < len(table); {
+		end := start + 1
+		for end < len(table) && table[end].Points == table[start].Points {
+			end++
+		}
+
+		group := table[start:end]
+		h2h := headToHead(t, group)
+		sort.SliceStable(group, func(i, j int) bool {
+			a, b := group[i], group[j]
+			if h2h[a.Player.ID] != h2h[b.Player.ID] {
+				return h2h[a.Player.ID] > h2h[b.Player.ID]
+			}
+			if a.GamesWon-a.GamesLost != b.GamesWon-b.GamesLost {
+				return a.GamesWon-a.GamesLost > b.GamesWon-b.GamesLost
+			}
+			if a.GamesWon != b.GamesWon {
+				return a.GamesWon > b.GamesWon
+			}
+			return a.Seed < b.Seed
+		})
+
+		start = end
+	}
+
+	return table
+}
+
+// headToHead returns the points each player in group took in matches against
+// the other players in group.
+func headToHead(t *models.Tournament, group []*models.Standing) map[int]float64 {
+	inGroup := make(map[int]bool)
+	for _, s := range group {
+		inGroup[s.Player.ID] = true
+	}
+
+	points := make(map[int]float64)
+	for _, m := range t.Matches {
+		if m.Score == nil || !inGroup[m.P1.ID] || !inGroup[m.P2.ID] {
+			continue
+		}
+
+		switch {
+		case m.Score.P1 > m.Score.P2:
+			points[m.P1.ID]++
+		case m.Score.P2 > m.Score.P1:
+			points[m.P2.ID]++
+		default:
+			points[m.P1.ID] += 0.5
+			points[m.P2.ID] += 0.5
+		}
+	}
+
+	return points
+}
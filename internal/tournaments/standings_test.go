@@ -0,0 +1,129 @@
+package tournaments
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// result is a played match between the players seeded p1 and p2.
+func result(players []*models.TournamentPlayer, round, p1, p2, score1, score2 int) *models.TournamentMatch {
+	m := &models.TournamentMatch{
+		Round: round,
+		P1:    players[p1-1].Player,
+		P2:    players[p2-1].Player,
+		Score: &models.MatchScore{P1: score1, P2: score2},
+	}
+	switch {
+	case score1 > score2:
+		m.Winner = m.P1
+	case score2 > score1:
+		m.Winner = m.P2
+	}
+	return m
+}
+
+// ranking lists the IDs of the players in the order of the table.
+func ranking(table []*models.Standing) []int {
+	var ids []int
+	for _, s := range table {
+		ids = append(ids, s.Player.ID)
+	}
+	return ids
+}
+
+func TestStandings(t *testing.T) {
+	players := testPlayers(4)
+
+	tests := []struct {
+		name    string
+		matches []*models.TournamentMatch
+		want    []int
+	}{
+		{
+			name: "points",
+			matches: []*models.TournamentMatch{
+				result(players, 1, 1, 2, 0, 2),
+				result(players, 1, 3, 4, 1, 1),
+				result(players, 2, 2, 4, 3, 0),
+			},
+			want: []int{2, 3, 4, 1},
+		},
+		{
+			name: "head-to-head before game difference",
+			matches: []*models.TournamentMatch{
+				result(players, 1, 2, 1, 2, 1),
+				result(players, 1, 3, 4, 0, 0),
+				result(players, 2, 1, 3, 9, 0),
+				result(players, 2, 4, 2, 2, 0),
+			},
+			want: []int{4, 2, 1, 3},
+		},
+		{
+			name: "game difference",
+			matches: []*models.TournamentMatch{
+				result(players, 1, 1, 3, 2, 1),
+				result(players, 1, 2, 4, 3, 0),
+			},
+			want: []int{2, 1, 3, 4},
+		},
+		{
+			name: "games won",
+			matches: []*models.TournamentMatch{
+				result(players, 1, 1, 3, 2, 1),
+				result(players, 1, 2, 4, 3, 2),
+			},
+			want: []int{2, 1, 4, 3},
+		},
+		{
+			name: "seed",
+			matches: []*models.TournamentMatch{
+				result(players, 1, 2, 4, 2, 0),
+				result(players, 1, 1, 3, 2, 0),
+			},
+			want: []int{1, 2, 3, 4},
+		},
+		{
+			name: "bye",
+			matches: []*models.TournamentMatch{
+				bye(1, players[3].Player),
+				result(players, 1, 1, 2, 1, 1),
+			},
+			want: []int{4, 1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := standings(&models.Tournament{Players: players, Matches: tt.matches})
+			if got := ranking(table); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("standings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStandingsTally(t *testing.T) {
+	players := testPlayers(3)
+	table := standings(&models.Tournament{
+		Players: players,
+		Matches: []*models.TournamentMatch{
+			bye(1, players[2].Player),
+			result(players, 1, 1, 2, 3, 1),
+			bye(2, players[0].Player),
+			result(players, 2, 2, 3, 2, 2),
+		},
+	})
+
+	want := []*models.Standing{
+		{Player: players[0].Player, Seed: 1, Played: 1, Won: 1, GamesWon: 3, GamesLost: 1, Points: 2},
+		{Player: players[2].Player, Seed: 3, Played: 1, Drawn: 1, GamesWon: 2, GamesLost: 2, Points: 1.5},
+		{Player: players[1].Player, Seed: 2, Played: 2, Drawn: 1, Lost: 1, GamesWon: 3, GamesLost: 5, Points: 0.5},
+	}
+	for i, s := range table {
+		if *s != *want[i] {
+			t.Errorf("standing %d = %+v, want %+v", i+1, *s, *want[i])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package tournaments
+
+import (
+	"database/sql"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+func insert(tx *sql.Tx, t *models.Tournament) error {
+	query := `
+	INSERT INTO tournaments (leaderboard_id, name, format, rounds)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id
+	`
+	err := tx.QueryRow(query, t.LeaderboardID, t.Name, t.Format, t.Rounds).Scan(&t.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+	INSERT INTO tournament_players (tournament_id, player_id, seed)
+	VALUES ($1, $2, $3)
+	`
+	for _, p := range t.Players {
+		if _, err := tx.Exec(query, t.ID, p.Player.ID, p.Seed); err != nil {
+			return err
+		}
+	}
+
+	return insertMatches(tx, t, t.Matches)
+}
+
+func insertMatches(tx *sql.Tx, t *models.Tournament, matches []*models.TournamentMatch) error {
+	query := `
+	INSERT INTO tournament_matches (tournament_id, round, slot, player1_id, player2_id, winner_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
+	`
+	for _, m := range matches {
+		err := tx.QueryRow(query,
+			t.ID,
+			m.Round,
+			m.Slot,
+			playerID(m.P1),
+			playerID(m.P2),
+			playerID(m.Winner),
+		).Scan(&m.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func updateMatch(tx *sql.Tx, m *models.TournamentMatch) error {
+	var score1, score2, matchID sql.NullInt64
+	if m.Score != nil {
+		score1 = sql.NullInt64{Int64: int64(m.Score.P1), Valid: true}
+		score2 = sql.NullInt64{Int64: int64(m.Score.P2), Valid: true}
+	}
+	if m.MatchID != 0 {
+		matchID = sql.NullInt64{Int64: int64(m.MatchID), Valid: true}
+	}
+
+	query := `
+	UPDATE tournament_matches
+	SET
+		player1_id = $1,
+		player2_id = $2,
+		player1_score = $3,
+		player2_score = $4,
+		winner_id = $5,
+		match_id = $6
+	WHERE id = $7
+	`
+	_, err := tx.Exec(query,
+		playerID(m.P1),
+		playerID(m.P2),
+		score1,
+		score2,
+		playerID(m.Winner),
+		matchID,
+		m.ID,
+	)
+	return err
+}
+
+func updateWinner(tx *sql.Tx, t *models.Tournament) error {
+	query := `
+	UPDATE tournaments
+	SET winner_id = $1
+	WHERE id = $2
+	`
+	_, err := tx.Exec(query, playerID(t.Winner), t.ID)
+	return err
+}
+
+// load returns the tournament with its players and matches, or sql.ErrNoRows
+// if there is no such tournament on the leaderboard.
+func load(tx *sql.Tx, leaderboardID int, name string) (*models.Tournament, error) {
+	query := `
+	SELECT id, leaderboard_id, name, format, rounds, winner_id, created_at
+	FROM tournaments
+	WHERE leaderboard_id = $1 AND name = $2
+	`
+	t := &models.Tournament{}
+	var winnerID sql.NullInt64
+	err := tx.QueryRow(query, leaderboardID, name).Scan(
+		&t.ID,
+		&t.LeaderboardID,
+		&t.Name,
+		&t.Format,
+		&t.Rounds,
+		&winnerID,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+	SELECT tp.seed, p.id, p.username, p.elo
+	FROM tournament_players tp
+	JOIN players p ON p.id = tp.player_id
+	WHERE tp.tournament_id = $1
+	ORDER BY tp.seed
+	`
+	rows, err := tx.Query(query, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	players := make(map[int64]*models.Player)
+	for rows.Next() {
+		p := &models.TournamentPlayer{Player: &models.Player{}}
+		err := rows.Scan(
+			&p.Seed,
+			&p.Player.ID,
+			&p.Player.Username,
+			&p.Player.Elo,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Players = append(t.Players, p)
+		players[int64(p.Player.ID)] = p.Player
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	player := func(id sql.NullInt64) *models.Player {
+		if !id.Valid {
+			return nil
+		}
+		return players[id.Int64]
+	}
+	t.Winner = player(winnerID)
+
+	query = `
+	SELECT id, round, slot, player1_id, player2_id, player1_score, player2_score, winner_id, match_id
+	FROM tournament_matches
+	WHERE tournament_id = $1
+	ORDER BY round, slot
+	`
+	rows, err = tx.Query(query, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &models.TournamentMatch{}
+		var p1ID, p2ID, score1, score2, winnerID, matchID sql.NullInt64
+		err := rows.Scan(
+			&m.ID,
+			&m.Round,
+			&m.Slot,
+			&p1ID,
+			&p2ID,
+			&score1,
+			&score2,
+			&winnerID,
+			&matchID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		m.P1 = player(p1ID)
+		m.P2 = player(p2ID)
+		m.Winner = player(winnerID)
+		if score1.Valid && score2.Valid {
+			m.Score = &models.MatchScore{P1: int(score1.Int64), P2: int(score2.Int64)}
+		}
+		m.MatchID = int(matchID.Int64)
+
+		t.Matches = append(t.Matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func playerID(p *models.Player) sql.NullInt64 {
+	if p == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(p.ID), Valid: true}
+}
@@ -0,0 +1,134 @@
+package tournaments
+
+import (
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+// swissRounds is the default number of rounds of a Swiss tournament, enough
+// to separate a single winner from the field.
+func swissRounds(players int) int {
+	rounds := 0
+	for n := 1; n < players; n *= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// newSwiss pairs the first round of a Swiss tournament, the top half of the
+// seeds against the bottom half. Later rounds are paired by nextSwissRound
+// once the previous round is complete.
+func newSwiss(players []*models.TournamentPlayer, rounds int) (int, []*models.TournamentMatch) {
+	ranked := make([]*models.Player, 0, len(players))
+	for _, p := range players {
+		ranked = append(ranked, p.Player)
+	}
+
+	var round []*models.TournamentMatch
+	if len(ranked)%2 == 1 {
+		round = append(round, bye(1, ranked[len(ranked)-1]))
+		ranked = ranked[:len(ranked)-1]
+	}
+
+	half := len(ranked) / 2
+	for i := 0; i < half; i++ {
+		round = append(round, &models.TournamentMatch{
+			Round: 1,
+			P1:    ranked[i],
+			P2:    ranked[half+i],
+		})
+	}
+
+	numberSlots(round)
+	return rounds, round
+}
+
+// nextSwissRound pairs players with the same or the closest score against
+// each other, avoiding rematches where possible. With an odd number of
+// players, the lowest ranked player who hasn't had a bye yet gets one.
+func nextSwissRound(t *models.Tournament, roundNumber int) []*models.TournamentMatch {
+	met := make(map[[2]int]bool)
+	hadBye := make(map[int]bool)
+	for _, m := range t.Matches {
+		if isBye(m) {
+			hadBye[m.P1.ID] = true
+			continue
+		}
+		met[[2]int{m.P1.ID, m.P2.ID}] = true
+		met[[2]int{m.P2.ID, m.P1.ID}] = true
+	}
+
+	var ranked []*models.Player
+	for _, s := range standings(t) {
+		ranked = append(ranked, s.Player)
+	}
+
+	var round []*models.TournamentMatch
+	if len(ranked)%2 == 1 {
+		i := len(ranked) - 1
+		for i > 0 && hadBye[ranked[i].ID] {
+			i--
+		}
+		round = append(round, bye(roundNumber, ranked[i]))
+		ranked = append(ranked[:i:i], ranked[i+1:]...)
+	}
+
+	pairs := pairAvoidingRematches(ranked, met)
+	if pairs == nil {
+		// Everyone has already met everyone they could be paired with, so
+		// rematches can't be avoided.
+		for i := 0; i < len(ranked); i += 2 {
+			pairs = append(pairs, [2]*models.Player{ranked[i], ranked[i+1]})
+		}
+	}
+
+	for _, pair := range pairs {
+		round = append(round, &models.TournamentMatch{
+			Round: roundNumber,
+			P1:    pair[0],
+			P2:    pair[1],
+		})
+	}
+
+	numberSlots(round)
+	return round
+}
+
+// pairAvoidingRematches pairs each player with the highest ranked player
+// below them they haven't met, backtracking when that leaves someone without
+// an opponent. It returns nil if there is no pairing without a rematch.
+func pairAvoidingRematches(ranked []*models.Player, met map[[2]int]bool) [][2]*models.Player {
+	if len(ranked) == 0 {
+		return [][2]*models.Player{}
+	}
+
+	first := ranked[0]
+	for i := 1; i < len(ranked); i++ {
+		if met[[2]int{first.ID, ranked[i].ID}] {
+			continue
+		}
+
+		rest := make([]*models.Player, 0, len(ranked)-2)
+		rest = append(rest, ranked[1:i]...)
+		rest = append(rest, ranked[i+1:]...)
+
+		if pairs := pairAvoidingRematches(rest, met); pairs != nil {
+			return append([][2]*models.Player{{first, ranked[i]}}, pairs...)
+		}
+	}
+
+	return nil
+}
+
+func bye(round int, p *models.Player) *models.TournamentMatch {
+	return &models.TournamentMatch{
+		Round:  round,
+		P1:     p,
+		Winner: p,
+	}
+}
+
+func numberSlots(round []*models.TournamentMatch) {
+	for slot, m := range round {
+		m.Slot = slot
+	}
+}
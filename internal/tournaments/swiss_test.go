@@ -0,0 +1,182 @@
+package tournaments
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+)
+
+func TestSwissRounds(t *testing.T) {
+	tests := []struct {
+		players int
+		want    int
+	}{
+		{players: 2, want: 1},
+		{players: 3, want: 2},
+		{players: 4, want: 2},
+		{players: 8, want: 3},
+		{players: 9, want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := swissRounds(tt.players); got != tt.want {
+			t.Errorf("swissRounds(%d) = %d, want %d", tt.players, got, tt.want)
+		}
+	}
+}
+
+func TestNewSwiss(t *testing.T) {
+	tests := []struct {
+		players int
+		want    []string
+	}{
+		{players: 4, want: []string{"1-3", "2-4"}},
+		{players: 5, want: []string{"5-", "1-3", "2-4"}},
+	}
+
+	for _, tt := range tests {
+		rounds, round := newSwiss(testPlayers(tt.players), 3)
+		if rounds != 3 {
+			t.Errorf("%d players: rounds = %d, want 3", tt.players, rounds)
+		}
+		if got := pairings(round, 1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d players: round 1 = %v, want %v", tt.players, got, tt.want)
+		}
+		for slot, m := range round {
+			if m.Slot != slot {
+				t.Errorf("%d players: slot = %d, want %d", tt.players, m.Slot, slot)
+			}
+		}
+	}
+}
+
+func TestNextSwissRound(t *testing.T) {
+	players := testPlayers(4)
+	odd := testPlayers(5)
+	three := testPlayers(3)
+
+	// Everyone has met everyone in the first three rounds.
+	_, allMet := newRoundRobin(players)
+	for _, m := range allMet {
+		m.Score = &models.MatchScore{P1: 2, P2: 0}
+		m.Winner = m.P1
+	}
+
+	tests := []struct {
+		name  string
+		t     *models.Tournament
+		round int
+		want  []string
+	}{
+		{
+			name: "by score",
+			t: &models.Tournament{Players: players, Matches: []*models.TournamentMatch{
+				result(players, 1, 1, 3, 2, 0),
+				result(players, 1, 2, 4, 2, 1),
+			}},
+			round: 2,
+			want:  []string{"1-2", "4-3"},
+		},
+		{
+			name: "avoiding rematches",
+			t: &models.Tournament{Players: players, Matches: []*models.TournamentMatch{
+				result(players, 1, 1, 3, 2, 0),
+				result(players, 1, 2, 4, 2, 0),
+				result(players, 2, 1, 2, 2, 0),
+				result(players, 2, 3, 4, 2, 0),
+			}},
+			round: 3,
+			want:  []string{"1-4", "2-3"},
+		},
+		{
+			name: "bye for the lowest ranked",
+			t: &models.Tournament{Players: odd, Matches: []*models.TournamentMatch{
+				bye(1, odd[4].Player),
+				result(odd, 1, 1, 3, 2, 0),
+				result(odd, 1, 2, 4, 2, 0),
+			}},
+			round: 2,
+			want:  []string{"4-", "1-2", "5-3"},
+		},
+		{
+			name: "bye for someone who hasn't had one",
+			t: &models.Tournament{Players: three, Matches: []*models.TournamentMatch{
+				bye(1, three[2].Player),
+				result(three, 1, 1, 2, 2, 0),
+				bye(2, three[1].Player),
+				result(three, 2, 3, 1, 2, 0),
+			}},
+			round: 3,
+			want:  []string{"1-", "3-2"},
+		},
+		{
+			name:  "forced rematch",
+			t:     &models.Tournament{Players: players, Matches: allMet},
+			round: 4,
+			want:  []string{"1-2", "3-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := nextSwissRound(tt.t, tt.round)
+			if got := pairings(round, tt.round); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("round %d = %v, want %v", tt.round, got, tt.want)
+			}
+			for slot, m := range round {
+				if m.Slot != slot {
+					t.Errorf("slot = %d, want %d", m.Slot, slot)
+				}
+			}
+		})
+	}
+}
+
+func TestPairAvoidingRematches(t *testing.T) {
+	players := testPlayers(4)
+	ranked := []*models.Player{players[0].Player, players[1].Player, players[2].Player, players[3].Player}
+
+	tests := []struct {
+		name string
+		met  [][2]int
+		want [][2]int
+	}{
+		{
+			name: "no one has met",
+			want: [][2]int{{1, 2}, {3, 4}},
+		},
+		{
+			name: "top two have met",
+			met:  [][2]int{{1, 2}},
+			want: [][2]int{{1, 3}, {2, 4}},
+		},
+		{
+			name: "backtracking",
+			met:  [][2]int{{3, 4}},
+			want: [][2]int{{1, 3}, {2, 4}},
+		},
+		{
+			name: "no pairing",
+			met:  [][2]int{{1, 2}, {1, 3}, {1, 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			met := make(map[[2]int]bool)
+			for _, pair := range tt.met {
+				met[pair] = true
+				met[[2]int{pair[1], pair[0]}] = true
+			}
+
+			var got [][2]int
+			for _, pair := range pairAvoidingRematches(ranked, met) {
+				got = append(got, [2]int{pair[0].ID, pair[1].ID})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pairs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package webhooks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// topPlayers is how many players of a leaderboard are shown in chats.
+const topPlayers = 10
+
+// card is an event laid out for a chat. Every chat format renders the same
+// card, so that all chats show the same results.
+type card struct {
+	Title  string
+	Text   string
+	Fields []*cardField
+	Footer string
+
+	// Mentions are the chat accounts of the players the event is about.
+	Mentions []string
+}
+
+type cardField struct {
+	Name  string
+	Value string
+}
+
+func newCard(e *webhook.Event) *card {
+	switch {
+	case e.Match != nil:
+		return matchCard(e)
+	case e.Type == webhook.LeaderboardViewed:
+		return leaderboardCard(e)
+	case e.Type == webhook.StatsViewed && len(e.Players) == 1:
+		return statsCard(e.Players[0])
+	default:
+		return &card{Text: e.Text}
+	}
+}
+
+func matchCard(e *webhook.Event) *card {
+	m := e.Match
+
+	c := &card{
+		Title: fmt.Sprintf("%s %d - %d %s", m.Player1.Username, m.Player1.Score, m.Player2.Score, m.Player2.Username),
+		Fields: []*cardField{
+			matchPlayerField(m.Player1),
+			matchPlayerField(m.Player2),
+		},
+		Footer: "Leaderboard " + e.Leaderboard,
+	}
+
+	// Tournaments and ladders follow the result with what it means for them.
+	if _, rest, _ := strings.Cut(e.Text, "\n"); strings.TrimSpace(rest) != "" {
+		c.Text = rest
+	}
+
+	switch {
+	case e.Type == webhook.Test:
+		c.Footer = "Test notification for leaderboard " + e.Leaderboard
+	case e.Tournament != nil:
+		c.Footer = fmt.Sprintf("Tournament %s on leaderboard %s", e.Tournament.Name, e.Leaderboard)
+	case e.Challenge != nil:
+		c.Footer = fmt.Sprintf("Challenge #%d on ladder %s", e.Challenge.ID, e.Leaderboard)
+	}
+
+	return c
+}
+
+func matchPlayerField(p *webhook.MatchPlayer) *cardField {
+	diff := p.EloAfter - p.EloBefore
+
+	arrow := "↔️"
+	switch {
+	case diff > 0:
+		arrow = "⬆️"
+	case diff < 0:
+		arrow = "⬇️"
+	}
+
+	value := fmt.Sprintf("%s %d → %d (%+d)", arrow, p.EloBefore, p.EloAfter, diff)
+	if streak := streak(p.CurrentStreak); streak != "" {
+		value += "\n" + streak
+	}
+
+	return &cardField{Name: p.Username, Value: value}
+}
+
+// streak describes a winning streak worth mentioning.
+func streak(wins int) string {
+	if wins < 3 {
+		return ""
+	}
+	return fmt.Sprintf("🔥 %d wins in a row", wins)
+}
+
+func leaderboardCard(e *webhook.Event) *card {
+	c := &card{Title: "Leaderboard " + e.Leaderboard}
+
+	if len(e.Players) == 0 {
+		c.Text = "No players yet."
+		return c
+	}
+
+	var lines []string
+	for _, p := range e.Players[:min(len(e.Players), topPlayers)] {
+		lines = append(lines, fmt.Sprintf("%s %s  %d Elo  ·  %dW %dD %dL",
+			medal(p.Rank),
+			p.Username,
+			p.Elo,
+			p.MatchesWon,
+			p.MatchesDrawn,
+			p.MatchesLost,
+		))
+	}
+	c.Text = strings.Join(lines, "\n")
+
+	if more := len(e.Players) - topPlayers; more > 0 {
+		c.Footer = fmt.Sprintf("and %d more", more)
+	}
+
+	return c
+}
+
+func medal(rank int) string {
+	switch rank {
+	case 1:
+		return "🥇"
+	case 2:
+		return "🥈"
+	case 3:
+		return "🥉"
+	default:
+		return fmt.Sprintf("%d.", rank)
+	}
+}
+
+func statsCard(p *webhook.Player) *card {
+	played := p.MatchesWon + p.MatchesDrawn + p.MatchesLost
+	winRatio := 0.
+	if played > 0 {
+		winRatio = float64(p.MatchesWon) / float64(played) * 100
+	}
+
+	return &card{
+		Title: p.Username + "'s Stats",
+		Fields: []*cardField{
+			{"Elo", fmt.Sprint(p.Elo)},
+			{"Win Ratio", fmt.Sprintf("%.2f%%", winRatio)},
+			{"Matches", fmt.Sprintf("%dW %dD %dL", p.MatchesWon, p.MatchesDrawn, p.MatchesLost)},
+			{"Games", fmt.Sprintf("%d won, %d lost", p.GamesWon, p.GamesLost)},
+		},
+		Footer: streak(p.CurrentStreak),
+	}
+}
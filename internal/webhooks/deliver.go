@@ -0,0 +1,436 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+const (
+	maxAttempts    = 5
+	attemptTimeout = 10 * time.Second
+	baseBackoff    = time.Second
+	maxBackoff     = time.Minute
+
+	// maxRetryAfter caps how long a receiver can make us hold on to a message
+	// through the Retry-After header.
+	maxRetryAfter = 10 * time.Minute
+
+	// claimLease is how long a claimed message is hidden from other
+	// dispatchers. A message whose dispatcher dies mid-delivery becomes
+	// visible again once the lease runs out, which makes delivery at least
+	// once rather than at most once.
+	claimLease = attemptTimeout + time.Minute
+
+	pollInterval = time.Second
+	batchSize    = 50
+
+	// disableAfter is how many delivery attempts in a row can fail before the
+	// webhook is disabled.
+	disableAfter = 15
+
+	// responseSnippet is how much of the response body is kept in the
+	// delivery log.
+	responseSnippet = 512
+)
+
+// Enqueue adds the event to the outbox of every enabled webhook on the
+// leaderboard that subscribes to it. It is meant to be called in the same
+// transaction as the change it announces, so that the event is sent if and
+// only if the change is committed.
+func Enqueue(tx *sql.Tx, leaderboardID int, e *webhook.Event) error {
+	if err := stamp(e); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO webhook_outbox (webhook_id, event, payload)
+	SELECT id, $2, $3 FROM webhooks
+	WHERE leaderboard_id = $1 AND enabled AND (events IS NULL OR $2 = ANY(events))
+	`
+	_, err = tx.Exec(query, leaderboardID, e.Type, payload)
+	return err
+}
+
+// stamp gives the event its id, version and time.
+func stamp(e *webhook.Event) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	e.Version = webhook.SchemaVersion
+	e.ID = hex.EncodeToString(id)
+	e.Timestamp = time.Now().UTC()
+
+	return nil
+}
+
+type outboxMessage struct {
+	id        int
+	webhookID int
+	url       string
+	secret    string
+	format    string
+	event     string
+	payload   []byte
+	attempts  int
+}
+
+// attempt is the outcome of a single delivery attempt.
+type attempt struct {
+	status     int
+	response   string
+	latency    time.Duration
+	retry      bool
+	retryAfter time.Duration
+	err        error
+}
+
+// Dispatch delivers the messages in the outbox, retrying failed deliveries
+// with exponential backoff. It never returns.
+func Dispatch(db *sql.DB, guard *Guard) {
+	for {
+		n, err := dispatch(db, guard)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+		}
+
+		// Keep going straight away while there is a backlog.
+		if n < batchSize {
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+func dispatch(db *sql.DB, guard *Guard) (int, error) {
+	query := `
+	WITH claimed AS (
+		UPDATE webhook_outbox
+		SET attempts = attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $1)
+		WHERE id IN (
+			SELECT o.id FROM webhook_outbox o
+			JOIN webhooks w ON w.id = o.webhook_id
+			WHERE o.delivered_at IS NULL AND o.failed_at IS NULL AND o.next_attempt_at <= CURRENT_TIMESTAMP AND w.enabled
+			ORDER BY o.id
+			LIMIT $2
+			FOR UPDATE OF o SKIP LOCKED
+		)
+		RETURNING id, webhook_id, event, payload, attempts
+	)
+	SELECT c.id, c.webhook_id, w.url, w.secret, w.format, c.event, c.payload, c.attempts
+	FROM claimed c
+	JOIN webhooks w ON w.id = c.webhook_id
+	`
+
+	rows, err := db.Query(query, claimLease.Seconds(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var messages []*outboxMessage
+	for rows.Next() {
+		m := &outboxMessage{}
+		if err := rows.Scan(&m.id, &m.webhookID, &m.url, &m.secret, &m.format, &m.event, &m.payload, &m.attempts); err != nil {
+			return 0, err
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range messages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := deliver(db, guard, m); err != nil {
+				log.Printf("err: %v\n", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return len(messages), nil
+}
+
+// deliver makes a delivery attempt and records its outcome in the outbox and
+// the delivery log. Network errors, 5xx and 429 responses are retried until
+// maxAttempts is reached, and so are messages that couldn't be prepared for
+// a reason that may go away. A webhook whose deliveries keep failing is
+// disabled.
+func deliver(db *sql.DB, guard *Guard, m *outboxMessage) (err error) {
+	body, a := prepare(db, m)
+	if a == nil {
+		a = post(guard.client, m.url, m.secret, body)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	if err = logDelivery(tx, m, a); err != nil {
+		return err
+	}
+
+	if a.err == nil {
+		log.Printf("Notified %s", m.url)
+
+		query := `
+		UPDATE webhook_outbox
+		SET delivered_at = CURRENT_TIMESTAMP, last_error = NULL
+		WHERE id = $1
+		`
+		if _, err = tx.Exec(query, m.id); err != nil {
+			return err
+		}
+
+		query = `
+		UPDATE webhooks
+		SET consecutive_failures = 0
+		WHERE id = $1
+		`
+		_, err = tx.Exec(query, m.webhookID)
+		return err
+	}
+
+	disabled, err := fail(tx, m)
+	if err != nil {
+		return err
+	}
+
+	if disabled || !a.retry || m.attempts >= maxAttempts {
+		log.Printf("Unable to notify webhook %s after %d attempt(s): %v", m.url, m.attempts, a.err)
+
+		query := `
+		UPDATE webhook_outbox
+		SET failed_at = CURRENT_TIMESTAMP, last_error = $1
+		WHERE id = $2
+		`
+		_, err = tx.Exec(query, a.err.Error(), m.id)
+		return err
+	}
+
+	wait := max(backoff(m.attempts), a.retryAfter)
+
+	log.Printf("Retrying webhook %s in %s: %v", m.url, wait, a.err)
+
+	query := `
+	UPDATE webhook_outbox
+	SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $1), last_error = $2
+	WHERE id = $3
+	`
+	_, err = tx.Exec(query, wait.Seconds(), a.err.Error(), m.id)
+	return err
+}
+
+// prepare renders the message in the webhook's format. If it can't, the
+// failed attempt is returned instead: errors reading templates or linked
+// accounts are worth retrying, while a payload or format that can't be
+// rendered never will be.
+func prepare(db *sql.DB, m *outboxMessage) ([]byte, *attempt) {
+	e := &webhook.Event{}
+	if err := json.Unmarshal(m.payload, e); err != nil {
+		return nil, &attempt{err: fmt.Errorf("invalid payload: %w", err)}
+	}
+
+	custom, err := applyTemplate(db, m.webhookID, e)
+	if err != nil {
+		return nil, &attempt{retry: true, err: err}
+	}
+
+	ids, err := mentions(db, m.webhookID, m.format, e)
+	if err != nil {
+		return nil, &attempt{retry: true, err: err}
+	}
+
+	body, err := encode(m.format, e, custom, ids)
+	if err != nil {
+		return nil, &attempt{err: err}
+	}
+
+	return body, nil
+}
+
+func logDelivery(tx *sql.Tx, m *outboxMessage, a *attempt) error {
+	query := `
+	INSERT INTO webhook_deliveries (webhook_id, outbox_id, event, attempt, status_code, latency_ms, error, response)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	// Test notifications don't go through the outbox.
+	outboxID := sql.NullInt64{Int64: int64(m.id), Valid: m.id != 0}
+	status := sql.NullInt64{Int64: int64(a.status), Valid: a.status != 0}
+	response := sql.NullString{String: a.response, Valid: a.status != 0}
+	var deliveryErr sql.NullString
+	if a.err != nil {
+		deliveryErr = sql.NullString{String: a.err.Error(), Valid: true}
+	}
+
+	_, err := tx.Exec(query,
+		m.webhookID,
+		outboxID,
+		m.event,
+		m.attempts,
+		status,
+		a.latency.Milliseconds(),
+		deliveryErr,
+		response,
+	)
+	return err
+}
+
+// fail counts a failed attempt against the webhook and disables it once
+// disableAfter attempts in a row have failed. Messages still waiting for a
+// disabled webhook are given up on, so that re-enabling it doesn't replay
+// a backlog of stale notifications.
+func fail(tx *sql.Tx, m *outboxMessage) (bool, error) {
+	query := `
+	UPDATE webhooks
+	SET
+		consecutive_failures = consecutive_failures + 1,
+		enabled = enabled AND consecutive_failures + 1 < $1,
+		disabled_at = CASE WHEN enabled AND consecutive_failures + 1 >= $1 THEN CURRENT_TIMESTAMP ELSE disabled_at END
+	WHERE id = $2
+	RETURNING enabled, consecutive_failures
+	`
+
+	var enabled bool
+	var failures int
+	if err := tx.QueryRow(query, disableAfter, m.webhookID).Scan(&enabled, &failures); err != nil {
+		return false, err
+	}
+	if enabled {
+		return false, nil
+	}
+
+	log.Printf("Disabled webhook %s after %d failed attempts in a row", m.url, failures)
+
+	return true, abandon(tx, m.webhookID)
+}
+
+// abandon gives up on the messages still waiting for a webhook.
+func abandon(tx *sql.Tx, webhookID int) error {
+	query := `
+	UPDATE webhook_outbox
+	SET failed_at = CURRENT_TIMESTAMP, last_error = 'webhook disabled'
+	WHERE webhook_id = $1 AND delivered_at IS NULL AND failed_at IS NULL
+	`
+	_, err := tx.Exec(query, webhookID)
+	return err
+}
+
+// post makes a single delivery attempt, signed with the webhook's secret.
+// For a failed attempt it reports whether it is worth retrying and how long
+// the receiver asked us to wait.
+func post(client *http.Client, url, secret string, body []byte) *attempt {
+	ctx, cancel := context.WithTimeout(context.Background(), attemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &attempt{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Webhooks registered before payloads were signed have no secret until
+	// it is rotated.
+	if secret != "" {
+		now := time.Now()
+		req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, now, body))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		// Retrying a blocked destination won't unblock it.
+		return &attempt{latency: time.Since(start), retry: !errors.Is(err, errBlocked), err: err}
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, responseSnippet))
+	a := &attempt{
+		status:   resp.StatusCode,
+		response: strings.ToValidUTF8(string(snippet), ""),
+		latency:  time.Since(start),
+	}
+
+	// Drain the body so that the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		a.retry = true
+		a.retryAfter = retryAfter(resp.Header.Get("Retry-After"))
+		a.err = fmt.Errorf("unexpected status: %s", resp.Status)
+	default:
+		a.err = fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return a
+}
+
+// backoff doubles the wait with every attempt, up to maxBackoff. Half of it
+// is random so that retries from many messages don't all land at once.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	return d/2 + time.Duration(mathrand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date, up to maxRetryAfter. Dates in the past and headers that can't
+// be parsed don't ask for any wait.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds > int(maxRetryAfter/time.Second) {
+			return maxRetryAfter
+		}
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(header); err == nil {
+		return min(max(time.Until(t), 0), maxRetryAfter)
+	}
+
+	return 0
+}
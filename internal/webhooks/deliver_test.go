@@ -0,0 +1,80 @@
+package webhooks
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 2, max: 2 * time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 5, max: 16 * time.Second},
+		{attempt: 7, max: maxBackoff},
+		{attempt: 100, max: maxBackoff},
+	}
+
+	for _, tt := range tests {
+		var lowest, highest time.Duration
+		for i := 0; i < 1000; i++ {
+			d := backoff(tt.attempt)
+			if d < tt.max/2 || d > tt.max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.max/2, tt.max)
+			}
+			if i == 0 || d < lowest {
+				lowest = d
+			}
+			if d > highest {
+				highest = d
+			}
+		}
+
+		// The jitter should spread retries over most of the range.
+		if highest-lowest < tt.max/4 {
+			t.Errorf("backoff(%d) only ranges from %s to %s", tt.attempt, lowest, highest)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		min, max time.Duration
+	}{
+		{name: "empty", header: ""},
+		{name: "seconds", header: "120", min: 2 * time.Minute, max: 2 * time.Minute},
+		{name: "zero seconds", header: "0"},
+		{name: "negative seconds", header: "-30"},
+		{name: "too many seconds", header: "86400", min: maxRetryAfter, max: maxRetryAfter},
+		{name: "overflowing seconds", header: "99999999999999999", min: maxRetryAfter, max: maxRetryAfter},
+		{
+			name:   "date",
+			header: time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat),
+			min:    time.Minute,
+			max:    2 * time.Minute,
+		},
+		{name: "past date", header: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+		{
+			name:   "distant date",
+			header: time.Now().Add(24 * time.Hour).UTC().Format(http.TimeFormat),
+			min:    maxRetryAfter,
+			max:    maxRetryAfter,
+		},
+		{name: "garbage", header: "soon"},
+		{name: "fraction", header: "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.header); got < tt.min || got > tt.max {
+				t.Errorf("retryAfter(%q) = %s, want between %s and %s", tt.header, got, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"strings"
+	"time"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// Discord rejects embeds over these limits.
+const (
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxFieldValue  = 1024
+)
+
+const (
+	discordGreen   = 0x2ecc71
+	discordBlurple = 0x5865f2
+)
+
+type discordMessage struct {
+	Content         string                  `json:"content,omitempty"`
+	Embeds          []*discordEmbed         `json:"embeds"`
+	AllowedMentions *discordAllowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+	Users []string `json:"users,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Color       int             `json:"color"`
+	Fields      []*discordField `json:"fields,omitempty"`
+	Footer      *discordFooter  `json:"footer,omitempty"`
+	Timestamp   string          `json:"timestamp,omitempty"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discord renders the event's card as a Discord webhook message with an
+// embed.
+func discord(e *webhook.Event, c *card) *discordMessage {
+	embed := &discordEmbed{
+		Title:       clip(c.Title, discordMaxTitle),
+		Description: clip(c.Text, discordMaxDescription),
+		Color:       discordBlurple,
+		Timestamp:   e.Timestamp.Format(time.RFC3339),
+	}
+	if e.Match != nil {
+		embed.Color = discordGreen
+	}
+
+	for _, f := range c.Fields {
+		embed.Fields = append(embed.Fields, &discordField{
+			Name:   f.Name,
+			Value:  clip(f.Value, discordMaxFieldValue),
+			Inline: true,
+		})
+	}
+
+	if c.Footer != "" {
+		embed.Footer = &discordFooter{Text: c.Footer}
+	}
+
+	msg := &discordMessage{Embeds: []*discordEmbed{embed}}
+
+	// Mentions in embeds don't ping anyone, so the players are mentioned in
+	// the content, and only they are.
+	if len(c.Mentions) > 0 {
+		for _, id := range c.Mentions {
+			msg.Content += "<@" + id + "> "
+		}
+		msg.Content = strings.TrimSpace(msg.Content)
+		msg.AllowedMentions = &discordAllowedMentions{Parse: []string{}, Users: c.Mentions}
+	}
+
+	return msg
+}
+
+// clip shortens s to at most n runes, keeping its lines.
+func clip(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// NewEvent starts an event on the leaderboard. text is the message sent to
+// chat webhooks; the caller fills in the structured fields.
+func NewEvent(eventType, leaderboard, text string) *webhook.Event {
+	return &webhook.Event{
+		Type:        eventType,
+		Leaderboard: leaderboard,
+		Text:        text,
+	}
+}
+
+func Player(p *models.Player) *webhook.Player {
+	return &webhook.Player{
+		Username:       p.Username,
+		MatchesWon:     p.MatchesWon,
+		MatchesDrawn:   p.MatchesDrawn,
+		MatchesLost:    p.MatchesLost,
+		GamesWon:       p.TotalGamesWon,
+		GamesLost:      p.TotalGamesLost,
+		CurrentStreak:  p.CurrentStreak,
+		Elo:            p.Elo,
+		LadderPosition: p.LadderPosition,
+	}
+}
+
+func Match(result *models.MatchResult) *webhook.Match {
+	m := &webhook.Match{
+		ID:    result.ID,
+		Score: fmt.Sprintf("%d-%d", result.Score.P1, result.Score.P2),
+		Player1: &webhook.MatchPlayer{
+			Username:      result.P1.Username,
+			Score:         result.Score.P1,
+			EloBefore:     result.P1.Elo - result.P1EloDiff,
+			EloAfter:      result.P1.Elo,
+			CurrentStreak: result.P1.CurrentStreak,
+		},
+		Player2: &webhook.MatchPlayer{
+			Username:      result.P2.Username,
+			Score:         result.Score.P2,
+			EloBefore:     result.P2.Elo - result.P2EloDiff,
+			EloAfter:      result.P2.Elo,
+			CurrentStreak: result.P2.CurrentStreak,
+		},
+	}
+
+	switch {
+	case result.Score.P1 > result.Score.P2:
+		m.Winner = result.P1.Username
+	case result.Score.P2 > result.Score.P1:
+		m.Winner = result.P2.Username
+	}
+
+	return m
+}
+
+func Challenge(c *models.Challenge) *webhook.Challenge {
+	return &webhook.Challenge{
+		ID:                 c.ID,
+		Status:             c.Status,
+		Challenger:         c.Challenger.Username,
+		ChallengerPosition: c.Challenger.LadderPosition,
+		Challenged:         c.Challenged.Username,
+		ChallengedPosition: c.Challenged.LadderPosition,
+		ExpiresAt:          c.ExpiresAt,
+		MatchID:            c.MatchID,
+	}
+}
+
+// sample makes up an event of the given type on the leaderboard, with every
+// field its type carries filled in. It is sent to test webhooks, and used to
+// check and preview message templates.
+func sample(eventType, leaderboard string) *webhook.Event {
+	match := &webhook.Match{
+		ID:     1,
+		Score:  "2-1",
+		Winner: "player1",
+		Player1: &webhook.MatchPlayer{
+			Username:      "player1",
+			Score:         2,
+			EloBefore:     1000,
+			EloAfter:      1016,
+			CurrentStreak: 3,
+		},
+		Player2: &webhook.MatchPlayer{
+			Username:  "player2",
+			Score:     1,
+			EloBefore: 1000,
+			EloAfter:  984,
+		},
+	}
+
+	player := func(username string, rank, elo int) *webhook.Player {
+		return &webhook.Player{
+			Username:       username,
+			Rank:           rank,
+			MatchesWon:     5,
+			MatchesDrawn:   1,
+			MatchesLost:    2,
+			GamesWon:       11,
+			GamesLost:      6,
+			CurrentStreak:  3,
+			Elo:            elo,
+			LadderPosition: rank,
+		}
+	}
+
+	challenge := &webhook.Challenge{
+		ID:                 1,
+		Status:             "pending",
+		Challenger:         "player1",
+		ChallengerPosition: 2,
+		Challenged:         "player2",
+		ChallengedPosition: 1,
+		ExpiresAt:          "2025-01-08 12:00",
+	}
+
+	summary := fmt.Sprintf("Match recorded: (%+d) player1 2 - 1 player2 (%+d) !\n", 16, -16)
+
+	e := NewEvent(eventType, leaderboard, "")
+	switch eventType {
+	case webhook.LeaderboardViewed:
+		e.Text = fmt.Sprintf("Leaderboard %s: player1, player2, player3\n", leaderboard)
+		e.Players = []*webhook.Player{
+			player("player1", 1, 1048),
+			player("player2", 2, 1016),
+			player("player3", 3, 984),
+		}
+	case webhook.PlayerCreated:
+		e.Text = fmt.Sprintf("Created player on leaderboard %s: player1\n", leaderboard)
+		e.Players = []*webhook.Player{{Username: "player1", Elo: 1000, LadderPosition: 1}}
+	case webhook.StatsViewed:
+		e.Text = "player1's Stats: 5W 1D 2L, 1048 Elo\n"
+		e.Players = []*webhook.Player{player("player1", 0, 1048)}
+	case webhook.MatchRecorded, webhook.Test:
+		e.Text = summary
+		e.Match = match
+	case webhook.TournamentMatchRecorded:
+		e.Text = summary
+		e.Match = match
+		e.Tournament = &webhook.Tournament{Name: "cup", Format: "single-elimination", Winner: "player1"}
+	case webhook.ChallengeIssued, webhook.ChallengeAccepted, webhook.ChallengeForfeited:
+		e.Text = fmt.Sprintf("Challenge #1: player1 (#2) challenged player2 (#1) on ladder %s!\n", leaderboard)
+		e.Challenge = challenge
+		switch eventType {
+		case webhook.ChallengeAccepted:
+			challenge.Status = "accepted"
+		case webhook.ChallengeForfeited:
+			challenge.Status = "forfeited"
+			challenge.ChallengerPosition, challenge.ChallengedPosition = 1, 2
+		}
+	case webhook.ChallengePlayed:
+		e.Text = summary
+		e.Match = match
+		e.Challenge = challenge
+		challenge.Status = "played"
+		challenge.MatchID = match.ID
+		challenge.ChallengerPosition, challenge.ChallengedPosition = 1, 2
+	case webhook.PairingsSuggested:
+		e.Text = fmt.Sprintf("Today's suggested matches on leaderboard %s:\nplayer1 (55%%) vs player2 (45%%)\n", leaderboard)
+		e.Pairings = []*webhook.Pairing{{Player1: "player1", Player2: "player2", WinProbability: 0.55}}
+	}
+
+	if eventType == webhook.Test {
+		e.Text = fmt.Sprintf("Test notification from leaderboard %s: %s", leaderboard, summary)
+	}
+
+	return e
+}
+
+// testEvent is the event sent to test a webhook. It holds a made up match, so
+// that the test shows what match results will look like.
+func testEvent(leaderboard string) *webhook.Event {
+	return sample(webhook.Test, leaderboard)
+}
+
+// parseEvents parses the events a webhook subscribes to, given as a comma
+// separated list. No events at all means every event, including ones added
+// later, and is reported as nil.
+func parseEvents(values []string) ([]string, error) {
+	var events []string
+	for _, value := range values {
+		for _, event := range strings.Split(value, ",") {
+			event = strings.TrimSpace(event)
+			if event == "" || slices.Contains(events, event) {
+				continue
+			}
+			if !slices.Contains(webhook.Events, event) {
+				return nil, fmt.Errorf("unknown event %s, expected one of: %s", event, strings.Join(webhook.Events, ", "))
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,139 @@
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+	"github.com/lib/pq"
+)
+
+// The formats notifications can be sent to a webhook in.
+const (
+	// Slack sends the event as a Slack Block Kit message.
+	Slack = "slack"
+
+	// Text sends the text of the event in the shape of a Slack incoming
+	// webhook message, for receivers that don't understand Block Kit.
+	Text = "text"
+
+	// Discord sends the event as a Discord webhook message with an embed.
+	Discord = "discord"
+
+	// Teams sends the event as a Microsoft Teams Adaptive Card.
+	Teams = "teams"
+
+	// JSON sends the event itself, following the published event schema.
+	JSON = "json"
+)
+
+var formats = []string{Slack, Text, Discord, Teams, JSON}
+
+// detectFormat picks the format for a webhook registered without one from
+// the host of its URL. Hosts we don't recognise get the Slack format, which
+// was the only one before formats could be chosen.
+func detectFormat(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Slack
+	}
+
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "discord.com" || host == "discordapp.com" || strings.HasSuffix(host, ".discord.com"):
+		return Discord
+	case strings.HasSuffix(host, ".webhook.office.com") || host == "outlook.office.com" || strings.HasSuffix(host, ".logic.azure.com"):
+		return Teams
+	default:
+		return Slack
+	}
+}
+
+// encode renders the event in the webhook's format. custom tells that the
+// text of the event comes from a template, in which case chats show just the
+// text rather than laying out the event themselves. mentions are the chat
+// accounts of the players to mention.
+func encode(format string, e *webhook.Event, custom bool, mentions []string) ([]byte, error) {
+	c := newCard(e)
+	if custom {
+		c = &card{Text: e.Text}
+	}
+	c.Mentions = mentions
+
+	switch format {
+	case Slack:
+		return json.Marshal(slack(e, c))
+	case Text:
+		if len(mentions) > 0 {
+			return json.Marshal(slackMessage{Text: slackMentions(mentions) + " " + e.Text})
+		}
+		return json.Marshal(slackMessage{Text: e.Text})
+	case Discord:
+		return json.Marshal(discord(e, c))
+	case Teams:
+		return json.Marshal(teams(e, c))
+	case JSON:
+		return json.Marshal(e)
+	default:
+		return nil, fmt.Errorf("unknown webhook format %s", format)
+	}
+}
+
+// mentions looks up the accounts the players an event is about have linked
+// on the chat the webhook posts to, so that they can be mentioned. Only
+// matches, challenges and pairings mention their players.
+func mentions(db *sql.DB, webhookID int, format string, e *webhook.Event) ([]string, error) {
+	var provider string
+	switch format {
+	case Slack, Text:
+		provider = "slack"
+	case Discord:
+		provider = "discord"
+	default:
+		return nil, nil
+	}
+
+	var usernames []string
+	switch {
+	case e.Match != nil:
+		usernames = append(usernames, e.Match.Player1.Username, e.Match.Player2.Username)
+	case e.Challenge != nil:
+		usernames = append(usernames, e.Challenge.Challenger, e.Challenge.Challenged)
+	case len(e.Pairings) > 0:
+		for _, p := range e.Pairings {
+			usernames = append(usernames, p.Player1, p.Player2)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	query := `
+	SELECT i.external_id
+	FROM identities i
+	JOIN players p ON p.id = i.player_id
+	JOIN webhooks w ON w.leaderboard_id = i.leaderboard_id
+	WHERE w.id = $1 AND i.provider = $2 AND p.username = ANY($3)
+	ORDER BY array_position($3, p.username::TEXT)
+	`
+
+	rows, err := db.Query(query, webhookID, provider, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
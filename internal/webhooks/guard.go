@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errBlocked is a webhook destination that pongo must not call, such as an
+// internal service or the cloud metadata endpoint.
+var errBlocked = errors.New("destination not allowed")
+
+// blockedPrefixes are the ranges that aren't reachable from the internet
+// but aren't covered by the netip.Addr predicates used in blocked.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+
+	// 6to4 and Teredo addresses embed an IPv4 address, which may well be a
+	// private one.
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("2001::/32"),
+}
+
+// Guard keeps webhooks from reaching private, loopback and link-local
+// addresses. URLs are checked when they are registered, and the address a
+// notification is actually sent to is checked again when connecting, so that
+// a host whose DNS changes after registration can't slip through.
+type Guard struct {
+	hosts    []string
+	prefixes []netip.Prefix
+	client   *http.Client
+}
+
+// NewGuard creates a guard that lets webhooks reach the hosts, addresses and
+// CIDR ranges in allowlist even if they are private.
+func NewGuard(allowlist []string) (*Guard, error) {
+	g := &Guard{}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid webhook allowlist entry %s: %w", entry, err)
+			}
+			g.prefixes = append(g.prefixes, prefix.Masked())
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			g.prefixes = append(g.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		g.hosts = append(g.hosts, entry)
+	}
+
+	checked := &net.Dialer{Timeout: attemptTimeout, Control: g.control}
+	unchecked := &net.Dialer{Timeout: attemptTimeout}
+
+	g.client = &http.Client{
+		Transport: &http.Transport{
+			// A proxy would hide the address we actually connect to.
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(addr)
+				if err == nil && g.hostAllowed(host) {
+					return unchecked.DialContext(ctx, network, addr)
+				}
+				return checked.DialContext(ctx, network, addr)
+			},
+			TLSHandshakeTimeout: attemptTimeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
+	return g, nil
+}
+
+// Check validates a webhook URL: it has to be http or https, and its host
+// has to resolve to addresses webhooks are allowed to reach.
+func (g *Guard) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("not a valid URL")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+
+	if u.User != nil {
+		return errors.New("credentials aren't allowed in the URL")
+	}
+
+	host := u.Hostname()
+	if host == "" {
+		return errors.New("missing host")
+	}
+
+	if g.hostAllowed(host) {
+		return nil
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.check(addr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("can't resolve %s", host)
+	}
+
+	for _, addr := range addrs {
+		if err := g.check(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// control checks the address a connection is about to be made to, after
+// DNS resolution.
+func (g *Guard) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return g.check(addrPort.Addr())
+}
+
+func (g *Guard) check(addr netip.Addr) error {
+	addr = addr.Unmap()
+
+	for _, prefix := range g.prefixes {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if blocked(addr) {
+		return fmt.Errorf("%w: %s is a private address", errBlocked, addr)
+	}
+
+	return nil
+}
+
+func (g *Guard) hostAllowed(host string) bool {
+	return slices.Contains(g.hosts, strings.ToLower(strings.TrimSuffix(host, ".")))
+}
+
+func blocked(addr netip.Addr) bool {
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,27 +1,36 @@
 package webhooks
 
 import (
-	"bytes"
+	"crypto/rand"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/6ixfigs/pingypongy/internal/models"
+	"github.com/6ixfigs/pingypongy/internal/render"
+	"github.com/6ixfigs/pingypongy/pkg/problem"
 	"github.com/go-chi/chi/v5"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/lib/pq"
 )
 
 type Handler struct {
-	Rtr chi.Router
-	db  *sql.DB
+	Rtr   chi.Router
+	db    *sql.DB
+	guard *Guard
 }
 
-func NewHandler(db *sql.DB) *Handler {
+func NewHandler(db *sql.DB, guard *Guard) *Handler {
 	return &Handler{
-		Rtr: chi.NewRouter(),
-		db:  db,
+		Rtr:   chi.NewRouter(),
+		db:    db,
+		guard: guard,
 	}
 }
 
@@ -29,22 +38,54 @@ func (h *Handler) MountRoutes() {
 	h.Rtr.Post("/", h.Register)
 	h.Rtr.Get("/", h.List)
 	h.Rtr.Delete("/", h.Delete)
+	h.Rtr.Get("/templates", h.Templates)
+	h.Rtr.Post("/templates/preview", h.PreviewTemplate)
+	h.Rtr.Put("/templates/{event}", h.SetTemplate)
+	h.Rtr.Delete("/templates/{event}", h.DeleteTemplate)
+	h.Rtr.Patch("/{webhook_id}", h.Update)
+	h.Rtr.Delete("/{webhook_id}", h.DeleteOne)
+	h.Rtr.Get("/{webhook_id}/deliveries", h.Deliveries)
+	h.Rtr.Post("/{webhook_id}/enable", h.Enable)
+	h.Rtr.Post("/{webhook_id}/rotate-secret", h.RotateSecret)
+	h.Rtr.Post("/{webhook_id}/test", h.Test)
+	h.Rtr.Put("/{webhook_id}/templates/{event}", h.SetTemplate)
+	h.Rtr.Delete("/{webhook_id}/templates/{event}", h.DeleteTemplate)
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Invalid request.", http.StatusBadRequest)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
 		return
 	}
 
 	name := chi.URLParam(r, "leaderboard_name")
 	url := r.FormValue("url")
 
+	if err := h.guard.Check(r.Context(), url); err != nil {
+		render.Error(w, r, problem.New(problem.InvalidURL, fmt.Sprintf("Invalid url: %s.", err.Error())))
+		return
+	}
+
+	events, err := parseEvents(r.Form["events"])
+	if err != nil {
+		render.Error(w, r, problem.New(problem.InvalidEvent, fmt.Sprintf("Invalid events: %s.", err.Error())))
+		return
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = detectFormat(url)
+	}
+	if err := validateFormat(format); err != nil {
+		render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid format: %s.", err.Error())))
+		return
+	}
+
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -68,53 +109,145 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
 	query = `
-	INSERT INTO webhooks (leaderboard_id, url)
-	VALUES ($1, $2)
+	INSERT INTO webhooks (leaderboard_id, url, events, secret, format)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
 	`
-	_, err = tx.Exec(query, l.ID, url)
+	secret, err := newSecret()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
-	response := fmt.Sprintf("Registered new webhook on leaderboard %s: %s\n", name, url)
+	var id int
+	err = tx.QueryRow(query, l.ID, url, pq.Array(events), secret, format).Scan(&id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			render.Error(w, r, problem.New(problem.WebhookExists, fmt.Sprintf("Webhook %s is already registered on leaderboard %s.", url, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Registered new webhook %d on leaderboard %s: %s\nFormat: %s\nSubscribed to: %s\n", id, name, url, format, subscriptions(events))
 
 	log.Print(response)
 
-	w.Write([]byte(response))
+	response += fmt.Sprintf("Signing secret: %s\nKeep it safe, it won't be shown again.\n", secret)
+
+	webhook := &models.Webhook{
+		ID:      id,
+		URL:     url,
+		Format:  format,
+		Enabled: true,
+		Events:  events,
+	}
+
+	render.Respond(w, r, response, &models.WebhookSecret{Webhook: webhook, Secret: secret})
 }
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	name := chi.URLParam(r, "leaderboard_name")
 
-	webhooks, err := All(h.db, name)
+	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	query := `
+	SELECT w.id, w.url, w.format, w.enabled, w.consecutive_failures, w.events
+	FROM leaderboards l
+	LEFT JOIN webhooks w ON w.leaderboard_id = l.id
+	WHERE l.name = $1
+	ORDER BY w.id
+	`
+
+	rows, err := tx.Query(query, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer rows.Close()
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"id", "url", "format", "status", "failures", "events"})
+	found, webhooks := false, []*models.Webhook{}
+	for rows.Next() {
+		found = true
+
+		var id sql.NullInt64
+		var url, format sql.NullString
+		var enabled sql.NullBool
+		var failures sql.NullInt64
+		var events []string
+		if err = rows.Scan(&id, &url, &format, &enabled, &failures, pq.Array(&events)); err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		if !id.Valid {
+			continue
+		}
+
+		webhook := &models.Webhook{
+			ID:                  int(id.Int64),
+			URL:                 url.String,
+			Format:              format.String,
+			Enabled:             enabled.Bool,
+			ConsecutiveFailures: int(failures.Int64),
+			Events:              events,
+		}
+
+		t.AppendRow(table.Row{
+			webhook.ID,
+			webhook.URL,
+			webhook.Format,
+			status(webhook),
+			webhook.ConsecutiveFailures,
+			subscriptions(webhook.Events),
+		})
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	if !found {
+		render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 		return
 	}
 
 	var response string
 	if len(webhooks) > 0 {
-		response = strings.Join(webhooks, "\n") + "\n"
+		response = fmt.Sprintf("Webhooks on leaderboard %s:\n```\n%s\n```\n", name, t.Render())
 	} else {
 		response = "No webhooks registered.\n"
 	}
 
-	w.Write([]byte(response))
+	render.Respond(w, r, response, webhooks)
 }
 
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -123,7 +256,7 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 	defer func() {
@@ -147,10 +280,10 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("err: %v\n", err)
 		if err == sql.ErrNoRows {
-			http.Error(w, fmt.Sprintf("Leaderboard %s does not exist.\n", name), http.StatusNotFound)
+			render.Error(w, r, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name)))
 			return
 		}
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
 		return
 	}
 
@@ -158,10 +291,17 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	DELETE FROM webhooks
 	WHERE leaderboard_id = $1
 	`
-	_, err = tx.Exec(query, l.ID)
+	result, err := tx.Exec(query, l.ID)
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		render.Error(w, r, err)
+		return
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
 		return
 	}
 
@@ -169,40 +309,121 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	log.Print(response)
 
-	w.Write([]byte(response))
+	render.Respond(w, r, response, &models.DeletedWebhooks{Deleted: count})
 }
 
-func Notify(webhooks []string, message string) {
-	type payload struct {
-		Text string `json:"text"`
+func (h *Handler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
 	}
 
-	p, err := json.Marshal(payload{message})
+	query := `
+	SELECT to_char(attempted_at, 'YYYY-MM-DD HH24:MI:SS'), event, attempt, status_code, latency_ms, error, response
+	FROM webhook_deliveries
+	WHERE webhook_id = $1
+	ORDER BY attempted_at DESC, id DESC
+	LIMIT 50
+	`
+
+	rows, err := tx.Query(query, webhook.ID)
 	if err != nil {
 		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
 	}
+	defer rows.Close()
 
-	for _, webhook := range webhooks {
-		req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(p))
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"attempted", "event", "attempt", "status", "latency", "error", "response"})
+	attempts := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &models.WebhookDelivery{WebhookID: webhook.ID}
+		var statusCode sql.NullInt64
+		var deliveryErr, response sql.NullString
+		err = rows.Scan(
+			&delivery.AttemptedAt,
+			&delivery.Event,
+			&delivery.Attempt,
+			&statusCode,
+			&delivery.LatencyMs,
+			&deliveryErr,
+			&response,
+		)
 		if err != nil {
 			log.Printf("err: %v\n", err)
-			continue
+			render.Error(w, r, err)
+			return
 		}
+		delivery.StatusCode = int(statusCode.Int64)
+		delivery.Error = deliveryErr.String
+		delivery.Response = response.String
 
-		if _, err := http.DefaultClient.Do(req); err != nil {
-			log.Printf("Unable to notify webhook %s", webhook)
-			continue
+		statusColumn := "-"
+		if delivery.StatusCode != 0 {
+			statusColumn = strconv.Itoa(delivery.StatusCode)
 		}
 
-		log.Printf("Notified %s", webhook)
+		t.AppendRow(table.Row{
+			delivery.AttemptedAt,
+			delivery.Event,
+			delivery.Attempt,
+			statusColumn,
+			fmt.Sprintf("%dms", delivery.LatencyMs),
+			truncate(delivery.Error, 40),
+			truncate(delivery.Response, 40),
+		})
+		attempts = append(attempts, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Webhook %d (%s) is %s with %d failed attempt(s) in a row.\n",
+		webhook.ID,
+		webhook.URL,
+		status(webhook),
+		webhook.ConsecutiveFailures,
+	)
+	if len(attempts) > 0 {
+		response += fmt.Sprintf("```\n%s\n```\n", t.Render())
+	} else {
+		response += "No deliveries yet.\n"
 	}
+
+	render.Respond(w, r, response, &models.WebhookDeliveries{Webhook: webhook, Deliveries: attempts})
 }
 
-func All(db *sql.DB, leaderboard string) ([]string, error) {
-	tx, err := db.Begin()
+func (h *Handler) Enable(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		return nil, err
+		render.Error(w, r, err)
+		return
 	}
 	defer func() {
 		if err != nil {
@@ -212,46 +433,686 @@ func All(db *sql.DB, leaderboard string) ([]string, error) {
 		}
 	}()
 
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
 	query := `
-	SELECT id, name FROM leaderboards
-	WHERE name = $1
+	UPDATE webhooks
+	SET enabled = TRUE, consecutive_failures = 0, disabled_at = NULL
+	WHERE id = $1
 	`
-	l := &models.Leaderboard{}
-	err = tx.QueryRow(query, leaderboard).Scan(
-		&l.ID,
-		&l.Name,
+	_, err = tx.Exec(query, webhook.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	webhook.Enabled = true
+	webhook.ConsecutiveFailures = 0
+	webhook.DisabledAt = ""
+
+	response := fmt.Sprintf("Enabled webhook %d on leaderboard %s: %s\n", webhook.ID, name, webhook.URL)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, webhook)
+}
+
+func (h *Handler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	UPDATE webhooks
+	SET secret = $1
+	WHERE id = $2
+	`
+	_, err = tx.Exec(query, secret, webhook.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Rotated the signing secret of webhook %d on leaderboard %s: %s\n", webhook.ID, name, webhook.URL)
+
+	log.Print(response)
+
+	response += fmt.Sprintf("Signing secret: %s\nKeep it safe, it won't be shown again.\n", secret)
+
+	render.Respond(w, r, response, &models.WebhookSecret{Webhook: webhook, Secret: secret})
+}
+
+// Test sends a sample event to the webhook and reports how the receiver
+// responded. It bypasses the outbox and the webhook's subscriptions, and is
+// sent even if the webhook is disabled.
+func (h *Handler) Test(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	// The webhook isn't kept locked while the receiver is waited on, so that
+	// the dispatcher and other requests can update it in the meantime.
+	webhook, err := h.lookup(name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	e := testEvent(name)
+	if err = stamp(e); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	body, err := encode(webhook.Format, e, false, nil)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	a := post(h.guard.client, webhook.URL, webhook.Secret, body)
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	m := &outboxMessage{webhookID: webhook.ID, event: e.Type, attempts: 1}
+	if err = logDelivery(tx, m, a); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	statusText := "no response"
+	if a.status != 0 {
+		statusText = fmt.Sprintf("%d %s", a.status, http.StatusText(a.status))
+	}
+
+	response := fmt.Sprintf("Sent a test notification to webhook %d on leaderboard %s: %s\nStatus: %s\nLatency: %dms\n",
+		webhook.ID,
+		name,
+		webhook.URL,
+		statusText,
+		a.latency.Milliseconds(),
 	)
+	if a.err != nil {
+		response += fmt.Sprintf("Error: %s\n", a.err.Error())
+	}
+	if a.response != "" {
+		response += fmt.Sprintf("Response: %s\n", truncate(a.response, 200))
+	}
+
+	log.Print(response)
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		Event:       e.Type,
+		Attempt:     1,
+		StatusCode:  a.status,
+		LatencyMs:   int(a.latency.Milliseconds()),
+		Response:    a.response,
+		AttemptedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if a.err != nil {
+		delivery.Error = a.err.Error()
+	}
+
+	// The receiver's failure is reported as a bad gateway, so that scripts
+	// can tell it apart from the test itself going wrong.
+	code := http.StatusOK
+	if a.err != nil {
+		code = http.StatusBadGateway
+	}
+
+	render.RespondStatus(w, r, code, response, delivery)
+}
+
+// newSecret generates a secret to sign a webhook's payloads with.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	tx, err := h.db.Begin()
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		return nil, err
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
 	}
 
-	query = `
-	SELECT url FROM webhooks
+	// Only the fields present in the request are changed.
+	if r.Form.Has("url") {
+		webhook.URL = r.FormValue("url")
+		if err := h.guard.Check(r.Context(), webhook.URL); err != nil {
+			render.Error(w, r, problem.New(problem.InvalidURL, fmt.Sprintf("Invalid url: %s.", err.Error())))
+			return
+		}
+	}
+
+	// As when registering, a new URL without a format picks the format
+	// from the URL.
+	if format := r.FormValue("format"); format != "" {
+		webhook.Format = format
+		if err := validateFormat(webhook.Format); err != nil {
+			render.Error(w, r, problem.New(problem.InvalidParameter, fmt.Sprintf("Invalid format: %s.", err.Error())))
+			return
+		}
+	} else if r.Form.Has("url") || r.Form.Has("format") {
+		webhook.Format = detectFormat(webhook.URL)
+	}
+
+	if r.Form.Has("events") {
+		events, err := parseEvents(r.Form["events"])
+		if err != nil {
+			render.Error(w, r, problem.New(problem.InvalidEvent, fmt.Sprintf("Invalid events: %s.", err.Error())))
+			return
+		}
+		webhook.Events = events
+	}
+
+	wasEnabled := webhook.Enabled
+	if r.Form.Has("enabled") {
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			render.Error(w, r, problem.New(problem.InvalidParameter, "Invalid enabled: expected true or false."))
+			return
+		}
+		webhook.Enabled = enabled
+	}
+
+	query := `
+	UPDATE webhooks
+	SET
+		url = $1,
+		format = $2,
+		events = $3,
+		enabled = $4,
+		consecutive_failures = CASE WHEN $4 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+		disabled_at = CASE WHEN $4 THEN NULL WHEN enabled THEN CURRENT_TIMESTAMP ELSE disabled_at END
+	WHERE id = $5
+	`
+	_, err = tx.Exec(query, webhook.URL, webhook.Format, pq.Array(webhook.Events), webhook.Enabled, webhook.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			render.Error(w, r, problem.New(problem.WebhookExists, fmt.Sprintf("Webhook %s is already registered on leaderboard %s.", webhook.URL, name)))
+			return
+		}
+		render.Error(w, r, err)
+		return
+	}
+
+	if wasEnabled && !webhook.Enabled {
+		err = abandon(tx, webhook.ID)
+		if err != nil {
+			log.Printf("err: %v\n", err)
+			render.Error(w, r, err)
+			return
+		}
+	}
+
+	response := fmt.Sprintf("Updated webhook %d on leaderboard %s: %s\nFormat: %s\nSubscribed to: %s\nStatus: %s\n",
+		webhook.ID,
+		name,
+		webhook.URL,
+		webhook.Format,
+		subscriptions(webhook.Events),
+		status(webhook),
+	)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, webhook)
+}
+
+func (h *Handler) DeleteOne(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	DELETE FROM webhooks
+	WHERE id = $1
+	`
+	_, err = tx.Exec(query, webhook.ID)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Deleted webhook %d on leaderboard %s: %s\n", webhook.ID, name, webhook.URL)
+
+	log.Print(response)
+
+	render.Respond(w, r, response, webhook)
+}
+
+func (h *Handler) Templates(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	leaderboardID, err := leaderboard(tx, name)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	query := `
+	SELECT id, COALESCE(webhook_id, 0), event, body, to_char(updated_at, 'YYYY-MM-DD HH24:MI')
+	FROM message_templates
 	WHERE leaderboard_id = $1
+	ORDER BY webhook_id NULLS FIRST, event
 	`
 
-	rows, err := tx.Query(query, l.ID)
+	rows, err := tx.Query(query, leaderboardID)
 	if err != nil {
 		log.Printf("err: %v\n", err)
-		return nil, err
+		render.Error(w, r, err)
+		return
 	}
 	defer rows.Close()
 
-	var webhooks []string
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"scope", "event", "template", "updated"})
+	templates := []*models.MessageTemplate{}
 	for rows.Next() {
-		var url string
-		if err := rows.Scan(&url); err != nil {
+		mt := &models.MessageTemplate{}
+		if err = rows.Scan(&mt.ID, &mt.WebhookID, &mt.Event, &mt.Body, &mt.UpdatedAt); err != nil {
 			log.Printf("err: %v\n", err)
-			return nil, err
+			render.Error(w, r, err)
+			return
 		}
-		webhooks = append(webhooks, url)
+
+		scope := "leaderboard"
+		if mt.WebhookID != 0 {
+			scope = fmt.Sprintf("webhook %d", mt.WebhookID)
+		}
+
+		t.AppendRow(table.Row{
+			scope,
+			mt.Event,
+			truncate(mt.Body, 60),
+			mt.UpdatedAt,
+		})
+		templates = append(templates, mt)
+	}
+	if err = rows.Err(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	var response string
+	if len(templates) > 0 {
+		response = fmt.Sprintf("Message templates on leaderboard %s:\n```\n%s\n```\n", name, t.Render())
+	} else {
+		response = "No message templates set.\n"
+	}
+
+	render.Respond(w, r, response, templates)
+}
+
+// SetTemplate sets the template for an event type on the leaderboard, or on
+// one of its webhooks. A webhook's templates take precedence over the
+// leaderboard's.
+func (h *Handler) SetTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+	eventType := chi.URLParam(r, "event")
+	body := r.FormValue("template")
+
+	if err := validateEvent(eventType); err != nil {
+		render.Error(w, r, problem.New(problem.InvalidEvent, fmt.Sprintf("Invalid event: %s.", err.Error())))
+		return
 	}
 
-	if err := rows.Err(); err != nil {
+	if _, err := preview(eventType, name, body); err != nil {
+		render.Error(w, r, problem.New(problem.InvalidTemplate, fmt.Sprintf("Invalid template: %s.", err.Error())))
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
 		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	leaderboardID, webhookID, err := templateScope(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	err = saveTemplate(tx, leaderboardID, webhookID, eventType, body)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	response := fmt.Sprintf("Set the %s template on %s\n", eventType, scopeName(name, webhookID))
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.MessageTemplate{WebhookID: webhookID, Event: eventType, Body: body})
+}
+
+func (h *Handler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "leaderboard_name")
+	id := chi.URLParam(r, "webhook_id")
+	eventType := chi.URLParam(r, "event")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	leaderboardID, webhookID, err := templateScope(tx, name, id)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+
+	deleted, err := deleteTemplate(tx, leaderboardID, webhookID, eventType)
+	if err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, err)
+		return
+	}
+	if !deleted {
+		render.Error(w, r, problem.New(problem.TemplateNotFound, fmt.Sprintf("No %s template is set on %s.", eventType, scopeName(name, webhookID))))
+		return
+	}
+
+	response := fmt.Sprintf("Deleted the %s template on %s\n", eventType, scopeName(name, webhookID))
+
+	log.Print(response)
+
+	render.Respond(w, r, response, &models.MessageTemplate{WebhookID: webhookID, Event: eventType})
+}
+
+// PreviewTemplate renders a template against sample data for its event type
+// without saving it.
+func (h *Handler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("err: %v\n", err)
+		render.Error(w, r, problem.New(problem.InvalidRequest, "Invalid request."))
+		return
+	}
+
+	name := chi.URLParam(r, "leaderboard_name")
+	eventType := r.FormValue("event")
+
+	if err := validateEvent(eventType); err != nil {
+		render.Error(w, r, problem.New(problem.InvalidEvent, fmt.Sprintf("Invalid event: %s.", err.Error())))
+		return
+	}
+
+	text, err := preview(eventType, name, r.FormValue("template"))
+	if err != nil {
+		render.Error(w, r, problem.New(problem.InvalidTemplate, fmt.Sprintf("Invalid template: %s.", err.Error())))
+		return
+	}
+
+	render.Respond(w, r, text, &models.TemplatePreview{Text: text})
+}
+
+// leaderboard looks up the id of the leaderboard.
+func leaderboard(tx *sql.Tx, name string) (int, error) {
+	query := `
+	SELECT id FROM leaderboards
+	WHERE name = $1
+	`
+
+	var id int
+	err := tx.QueryRow(query, name).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, problem.New(problem.LeaderboardNotFound, fmt.Sprintf("Leaderboard %s does not exist.", name))
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// find looks up the webhook with the given id on the leaderboard.
+func find(tx *sql.Tx, name, id string) (*models.Webhook, error) {
+	leaderboardID, err := leaderboard(tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, problem.New(problem.WebhookNotFound, fmt.Sprintf("Webhook %s does not exist on leaderboard %s.", id, name))
+	}
+
+	query := `
+	SELECT id, leaderboard_id, url, secret, format, events, enabled, consecutive_failures, to_char(created_at, 'YYYY-MM-DD HH24:MI'), COALESCE(to_char(disabled_at, 'YYYY-MM-DD HH24:MI'), '')
+	FROM webhooks
+	WHERE id = $1 AND leaderboard_id = $2
+	FOR UPDATE
+	`
+
+	webhook := &models.Webhook{}
+	err = tx.QueryRow(query, webhookID, leaderboardID).Scan(
+		&webhook.ID,
+		&webhook.LeaderboardID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.Format,
+		pq.Array(&webhook.Events),
+		&webhook.Enabled,
+		&webhook.ConsecutiveFailures,
+		&webhook.CreatedAt,
+		&webhook.DisabledAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, problem.New(problem.WebhookNotFound, fmt.Sprintf("Webhook %s does not exist on leaderboard %s.", id, name))
+		}
 		return nil, err
 	}
 
-	return webhooks, nil
+	return webhook, nil
+}
+
+// lookup reads a webhook in a transaction of its own, which is over by the
+// time it returns.
+func (h *Handler) lookup(name, id string) (*models.Webhook, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return find(tx, name, id)
+}
+
+// templateScope looks up the leaderboard and, if id isn't empty, the webhook
+// a template is set on. The webhook id is 0 for the leaderboard's templates.
+func templateScope(tx *sql.Tx, name, id string) (int, int, error) {
+	if id == "" {
+		leaderboardID, err := leaderboard(tx, name)
+		return leaderboardID, 0, err
+	}
+
+	webhook, err := find(tx, name, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return webhook.LeaderboardID, webhook.ID, nil
+}
+
+func scopeName(name string, webhookID int) string {
+	if webhookID == 0 {
+		return fmt.Sprintf("leaderboard %s", name)
+	}
+	return fmt.Sprintf("webhook %d on leaderboard %s", webhookID, name)
+}
+
+func validateFormat(format string) error {
+	if !slices.Contains(formats, format) {
+		return fmt.Errorf("expected one of: %s", strings.Join(formats, ", "))
+	}
+	return nil
+}
+
+// subscriptions describes the events a webhook subscribes to.
+func subscriptions(events []string) string {
+	if len(events) == 0 {
+		return "all events"
+	}
+	return strings.Join(events, ", ")
+}
+
+func status(webhook *models.Webhook) string {
+	if webhook.Enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// truncate shortens s to at most n runes on a single line, so that it fits
+// in a table cell.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return s
 }
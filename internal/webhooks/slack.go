@@ -0,0 +1,144 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// The action ids of the buttons on Slack match messages.
+const (
+	ActionConfirm = "match_confirm"
+	ActionDispute = "match_dispute"
+	ActionRematch = "match_rematch"
+)
+
+// MatchAction is the value of the buttons on Slack match messages. It tells
+// the Slack interactivity endpoint which match a button was clicked for.
+type MatchAction struct {
+	Leaderboard string `json:"leaderboard"`
+	Match       int    `json:"match"`
+	Player1     string `json:"player1"`
+	Player2     string `json:"player2"`
+}
+
+type slackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []*slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Fields   []*slackText `json:"fields,omitempty"`
+	Elements []any        `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackButton struct {
+	Type     string     `json:"type"`
+	Text     *slackText `json:"text"`
+	ActionID string     `json:"action_id"`
+	Value    string     `json:"value"`
+	Style    string     `json:"style,omitempty"`
+}
+
+// slack renders the event's card as a Block Kit message. The text of the
+// event is kept as the fallback shown in notifications.
+func slack(e *webhook.Event, c *card) *slackMessage {
+	msg := &slackMessage{Text: e.Text}
+
+	if c.Title != "" {
+		msg.Blocks = append(msg.Blocks, &slackBlock{
+			Type: "header",
+			Text: &slackText{Type: "plain_text", Text: c.Title},
+		})
+	}
+
+	if len(c.Mentions) > 0 {
+		msg.Blocks = append(msg.Blocks, &slackBlock{
+			Type:     "context",
+			Elements: []any{slackMrkdwn(slackMentions(c.Mentions))},
+		})
+	}
+
+	if len(c.Fields) > 0 {
+		b := &slackBlock{Type: "section"}
+		for _, f := range c.Fields {
+			b.Fields = append(b.Fields, slackMrkdwn(fmt.Sprintf("*%s*\n%s", f.Name, f.Value)))
+		}
+		msg.Blocks = append(msg.Blocks, b)
+	}
+
+	if c.Text != "" {
+		msg.Blocks = append(msg.Blocks, &slackBlock{Type: "section", Text: slackMrkdwn(c.Text)})
+	}
+
+	if c.Footer != "" {
+		msg.Blocks = append(msg.Blocks, &slackBlock{
+			Type:     "context",
+			Elements: []any{slackMrkdwn(c.Footer)},
+		})
+	}
+
+	// Plain matches can be confirmed or disputed by their players, and
+	// played again, from the message.
+	if e.Type == webhook.MatchRecorded && e.Match != nil && e.Match.ID != 0 {
+		msg.Blocks = append(msg.Blocks, slackMatchActions(e))
+	}
+
+	return msg
+}
+
+func slackMatchActions(e *webhook.Event) *slackBlock {
+	value, _ := json.Marshal(&MatchAction{
+		Leaderboard: e.Leaderboard,
+		Match:       e.Match.ID,
+		Player1:     e.Match.Player1.Username,
+		Player2:     e.Match.Player2.Username,
+	})
+
+	return &slackBlock{
+		Type: "actions",
+		Elements: []any{
+			&slackButton{
+				Type:     "button",
+				Text:     &slackText{Type: "plain_text", Text: "Confirm"},
+				ActionID: ActionConfirm,
+				Value:    string(value),
+				Style:    "primary",
+			},
+			&slackButton{
+				Type:     "button",
+				Text:     &slackText{Type: "plain_text", Text: "Dispute"},
+				ActionID: ActionDispute,
+				Value:    string(value),
+				Style:    "danger",
+			},
+			&slackButton{
+				Type:     "button",
+				Text:     &slackText{Type: "plain_text", Text: "Rematch"},
+				ActionID: ActionRematch,
+				Value:    string(value),
+			},
+		},
+	}
+}
+
+func slackMentions(userIDs []string) string {
+	var mentions []string
+	for _, id := range userIDs {
+		mentions = append(mentions, "<@"+id+">")
+	}
+	return strings.Join(mentions, " ")
+}
+
+func slackMrkdwn(text string) *slackText {
+	return &slackText{Type: "mrkdwn", Text: text}
+}
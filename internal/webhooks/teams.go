@@ -0,0 +1,98 @@
+package webhooks
+
+import (
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+type teamsMessage struct {
+	Type        string             `json:"type"`
+	Attachments []*teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string     `json:"contentType"`
+	Content     *teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string          `json:"$schema"`
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Body    []*teamsElement `json:"body"`
+}
+
+// teamsElement is an Adaptive Card element. Only the fields used by the
+// elements we send are listed.
+type teamsElement struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Size     string          `json:"size,omitempty"`
+	Weight   string          `json:"weight,omitempty"`
+	FontType string          `json:"fontType,omitempty"`
+	IsSubtle bool            `json:"isSubtle,omitempty"`
+	Wrap     bool            `json:"wrap,omitempty"`
+	Columns  []*teamsElement `json:"columns,omitempty"`
+	Items    []*teamsElement `json:"items,omitempty"`
+	Width    string          `json:"width,omitempty"`
+}
+
+// teams renders the event's card as an Adaptive Card for a Microsoft Teams
+// incoming webhook or workflow.
+func teams(e *webhook.Event, c *card) *teamsMessage {
+
+	var body []*teamsElement
+
+	if c.Title != "" {
+		body = append(body, &teamsElement{Type: "TextBlock", Text: c.Title, Size: "Large", Weight: "Bolder", Wrap: true})
+	}
+
+	if len(c.Fields) > 0 {
+		columns := &teamsElement{Type: "ColumnSet"}
+		for _, f := range c.Fields {
+			columns.Columns = append(columns.Columns, &teamsElement{
+				Type:  "Column",
+				Width: "stretch",
+				Items: []*teamsElement{
+					{Type: "TextBlock", Text: f.Name, Weight: "Bolder", Wrap: true},
+					// Adaptive Cards need a blank line to break a line.
+					{Type: "TextBlock", Text: strings.ReplaceAll(f.Value, "\n", "\n\n"), Wrap: true},
+				},
+			})
+		}
+		body = append(body, columns)
+	}
+
+	if c.Text != "" {
+		text := &teamsElement{Type: "TextBlock", Text: c.Text, Wrap: true}
+
+		// Adaptive Cards don't render code blocks, so tables are shown in a
+		// monospace font instead.
+		if strings.Contains(c.Text, "```") {
+			text.Text = strings.ReplaceAll(c.Text, "```", "")
+			text.FontType = "Monospace"
+		} else {
+			text.Text = strings.ReplaceAll(c.Text, "\n", "\n\n")
+		}
+
+		body = append(body, text)
+	}
+
+	if c.Footer != "" {
+		body = append(body, &teamsElement{Type: "TextBlock", Text: c.Footer, Size: "Small", IsSubtle: true, Wrap: true})
+	}
+
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []*teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: &teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/6ixfigs/pingypongy/pkg/webhook"
+)
+
+// maxTemplateSize is the longest template that can be saved, in bytes.
+const maxTemplateSize = 4000
+
+// templateFuncs are the functions templates can use besides the builtin ones.
+var templateFuncs = template.FuncMap{
+	// signed formats a number with its sign, like the Elo changes in the
+	// default messages.
+	"signed": func(n int) string {
+		return fmt.Sprintf("%+d", n)
+	},
+	// diff is how much a number changed, for example a player's Elo.
+	"diff": func(before, after int) int {
+		return after - before
+	},
+	// percent formats a probability as a percentage.
+	"percent": func(p float64) string {
+		return fmt.Sprintf("%.0f%%", p*100)
+	},
+}
+
+func parseTemplate(body string) (*template.Template, error) {
+	return template.New("message").Funcs(templateFuncs).Option("missingkey=error").Parse(body)
+}
+
+func execute(t *template.Template, e *webhook.Event) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, e); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// validateEvent checks that templates can be set for the event type.
+func validateEvent(eventType string) error {
+	if !slices.Contains(webhook.Events, eventType) {
+		return fmt.Errorf("expected one of: %s", strings.Join(webhook.Events, ", "))
+	}
+	return nil
+}
+
+// preview renders the template against sample data for the event type. It
+// is how templates are validated before they are saved, so that mistakes
+// like a misspelt field are reported then rather than at delivery.
+func preview(eventType, leaderboard, body string) (string, error) {
+	if len(body) > maxTemplateSize {
+		return "", fmt.Errorf("longer than %d bytes", maxTemplateSize)
+	}
+
+	t, err := parseTemplate(body)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := execute(t, sample(eventType, leaderboard))
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("renders an empty message")
+	}
+
+	return text, nil
+}
+
+// applyTemplate replaces the text of the event with the webhook's template
+// for its type, or else the leaderboard's, and reports whether there was one.
+// A template that fails to render leaves the default text in place.
+func applyTemplate(db *sql.DB, webhookID int, e *webhook.Event) (bool, error) {
+	query := `
+	SELECT t.body
+	FROM message_templates t
+	JOIN webhooks w ON w.leaderboard_id = t.leaderboard_id
+	WHERE w.id = $1 AND t.event = $2 AND (t.webhook_id = w.id OR t.webhook_id IS NULL)
+	ORDER BY t.webhook_id NULLS LAST
+	LIMIT 1
+	`
+
+	var body string
+	err := db.QueryRow(query, webhookID, e.Type).Scan(&body)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	t, err := parseTemplate(body)
+	if err != nil {
+		log.Printf("err: template for %s on webhook %d: %v\n", e.Type, webhookID, err)
+		return false, nil
+	}
+
+	text, err := execute(t, e)
+	if err != nil {
+		log.Printf("err: template for %s on webhook %d: %v\n", e.Type, webhookID, err)
+		return false, nil
+	}
+
+	e.Text = text
+	return true, nil
+}
+
+// saveTemplate sets the template for the event type on the leaderboard, or
+// on a single webhook if webhookID isn't 0.
+func saveTemplate(tx *sql.Tx, leaderboardID, webhookID int, eventType, body string) error {
+	query := `
+	INSERT INTO message_templates (leaderboard_id, webhook_id, event, body)
+	VALUES ($1, NULLIF($2, 0), $3, $4)
+	ON CONFLICT (leaderboard_id, COALESCE(webhook_id, 0), event)
+	DO UPDATE SET body = EXCLUDED.body, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := tx.Exec(query, leaderboardID, webhookID, eventType, body)
+	return err
+}
+
+// deleteTemplate removes the template for the event type from the
+// leaderboard, or from a single webhook if webhookID isn't 0, and reports
+// whether there was one.
+func deleteTemplate(tx *sql.Tx, leaderboardID, webhookID int, eventType string) (bool, error) {
+	query := `
+	DELETE FROM message_templates
+	WHERE leaderboard_id = $1 AND COALESCE(webhook_id, 0) = $2 AND event = $3
+	`
+	res, err := tx.Exec(query, leaderboardID, webhookID, eventType)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,3 @@
+DROP TABLE tournament_matches;
+DROP TABLE tournament_players;
+DROP TABLE tournaments;
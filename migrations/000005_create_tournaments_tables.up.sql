@@ -0,0 +1,32 @@
+CREATE TABLE tournaments (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	leaderboard_id INTEGER NOT NULL REFERENCES leaderboards(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	format VARCHAR(32) NOT NULL,
+	rounds INTEGER NOT NULL,
+	winner_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (leaderboard_id, name)
+);
+
+CREATE TABLE tournament_players (
+	tournament_id INTEGER NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+	player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+	seed INTEGER NOT NULL,
+	PRIMARY KEY (tournament_id, player_id),
+	UNIQUE (tournament_id, seed)
+);
+
+CREATE TABLE tournament_matches (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	tournament_id INTEGER NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+	round INTEGER NOT NULL,
+	slot INTEGER NOT NULL,
+	player1_id INTEGER REFERENCES players(id) ON DELETE CASCADE,
+	player2_id INTEGER REFERENCES players(id) ON DELETE CASCADE,
+	player1_score INTEGER,
+	player2_score INTEGER,
+	winner_id INTEGER REFERENCES players(id) ON DELETE CASCADE,
+	match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+	UNIQUE (tournament_id, round, slot)
+);
@@ -0,0 +1,9 @@
+DROP TABLE ladder_history;
+DROP TABLE challenges;
+
+ALTER TABLE players DROP COLUMN ladder_position;
+
+ALTER TABLE leaderboards
+	DROP COLUMN type,
+	DROP COLUMN challenge_range,
+	DROP COLUMN challenge_expiry_days;
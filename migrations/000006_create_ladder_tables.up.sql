@@ -0,0 +1,38 @@
+ALTER TABLE leaderboards
+	ADD COLUMN type VARCHAR(32) NOT NULL DEFAULT 'elo',
+	ADD COLUMN challenge_range INTEGER NOT NULL DEFAULT 3,
+	ADD COLUMN challenge_expiry_days INTEGER NOT NULL DEFAULT 7;
+
+ALTER TABLE players ADD COLUMN ladder_position INTEGER;
+
+UPDATE players
+SET ladder_position = ranked.position
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY leaderboard_id ORDER BY elo DESC, created_at) AS position
+	FROM players
+) ranked
+WHERE players.id = ranked.id;
+
+ALTER TABLE players ALTER COLUMN ladder_position SET NOT NULL;
+
+CREATE TABLE challenges (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	leaderboard_id INTEGER NOT NULL REFERENCES leaderboards(id) ON DELETE CASCADE,
+	challenger_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+	challenged_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	match_id INTEGER REFERENCES matches(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	accepted_at TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	resolved_at TIMESTAMP
+);
+
+CREATE TABLE ladder_history (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+	challenge_id INTEGER REFERENCES challenges(id) ON DELETE SET NULL,
+	old_position INTEGER NOT NULL,
+	new_position INTEGER NOT NULL,
+	changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,14 @@
+CREATE TABLE webhook_outbox (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	delivered_at TIMESTAMP,
+	failed_at TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhook_outbox_pending_idx ON webhook_outbox (next_attempt_at)
+WHERE delivered_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,8 @@
+DROP TABLE webhook_deliveries;
+
+ALTER TABLE webhook_outbox DROP COLUMN event;
+
+ALTER TABLE webhooks
+	DROP COLUMN enabled,
+	DROP COLUMN consecutive_failures,
+	DROP COLUMN disabled_at;
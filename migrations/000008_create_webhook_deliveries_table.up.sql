@@ -0,0 +1,21 @@
+ALTER TABLE webhooks
+	ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN disabled_at TIMESTAMP;
+
+ALTER TABLE webhook_outbox ADD COLUMN event VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE webhook_deliveries (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	outbox_id INTEGER REFERENCES webhook_outbox(id) ON DELETE SET NULL,
+	event VARCHAR(64) NOT NULL,
+	attempt INTEGER NOT NULL,
+	status_code INTEGER,
+	latency_ms INTEGER NOT NULL,
+	error TEXT,
+	response TEXT,
+	attempted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, attempted_at DESC);
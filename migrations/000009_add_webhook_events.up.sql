@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN events TEXT[];
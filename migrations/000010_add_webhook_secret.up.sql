@@ -0,0 +1 @@
+ALTER TABLE webhooks ADD COLUMN secret TEXT NOT NULL DEFAULT '';
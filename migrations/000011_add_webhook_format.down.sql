@@ -0,0 +1,9 @@
+ALTER TABLE webhook_outbox ADD COLUMN message TEXT;
+
+UPDATE webhook_outbox SET message = payload->>'text';
+
+ALTER TABLE webhook_outbox
+	ALTER COLUMN message SET NOT NULL,
+	DROP COLUMN payload;
+
+ALTER TABLE webhooks DROP COLUMN format;
@@ -0,0 +1,20 @@
+ALTER TABLE webhooks ADD COLUMN format VARCHAR(16) NOT NULL DEFAULT 'slack';
+
+ALTER TABLE webhook_outbox ADD COLUMN payload JSONB;
+
+UPDATE webhook_outbox o
+SET payload = jsonb_build_object(
+	'version', 1,
+	'id', 'outbox-' || o.id,
+	'type', o.event,
+	'leaderboard', l.name,
+	'timestamp', to_char(o.created_at, 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+	'text', o.message
+)
+FROM webhooks w
+JOIN leaderboards l ON l.id = w.leaderboard_id
+WHERE w.id = o.webhook_id;
+
+ALTER TABLE webhook_outbox
+	ALTER COLUMN payload SET NOT NULL,
+	DROP COLUMN message;
@@ -0,0 +1,12 @@
+CREATE TABLE message_templates (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	leaderboard_id INTEGER NOT NULL REFERENCES leaderboards(id) ON DELETE CASCADE,
+	webhook_id INTEGER REFERENCES webhooks(id) ON DELETE CASCADE,
+	event VARCHAR(64) NOT NULL,
+	body TEXT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A leaderboard-wide template has no webhook, and there is at most one of
+-- those per event too.
+CREATE UNIQUE INDEX message_templates_scope_idx ON message_templates (leaderboard_id, COALESCE(webhook_id, 0), event);
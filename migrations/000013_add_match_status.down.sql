@@ -0,0 +1,3 @@
+ALTER TABLE matches
+	DROP COLUMN status,
+	DROP COLUMN status_by;
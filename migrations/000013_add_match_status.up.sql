@@ -0,0 +1,3 @@
+ALTER TABLE matches
+	ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'recorded',
+	ADD COLUMN status_by INTEGER REFERENCES players(id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE identities (
+	id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	leaderboard_id INTEGER NOT NULL REFERENCES leaderboards(id) ON DELETE CASCADE,
+	player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+	provider VARCHAR(32) NOT NULL,
+	external_id VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (leaderboard_id, provider, external_id),
+	UNIQUE (player_id, provider)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE matches
+	ADD COLUMN recorded_by INTEGER REFERENCES players(id) ON DELETE SET NULL;
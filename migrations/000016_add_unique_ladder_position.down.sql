@@ -0,0 +1,2 @@
+ALTER TABLE players
+	DROP CONSTRAINT players_ladder_position_key;
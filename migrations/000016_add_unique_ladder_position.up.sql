@@ -0,0 +1,10 @@
+UPDATE players
+SET ladder_position = ranked.position
+FROM (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY leaderboard_id ORDER BY ladder_position, created_at, id) AS position
+	FROM players
+) ranked
+WHERE players.id = ranked.id AND players.ladder_position <> ranked.position;
+
+ALTER TABLE players
+	ADD CONSTRAINT players_ladder_position_key UNIQUE (leaderboard_id, ladder_position) DEFERRABLE INITIALLY DEFERRED;
@@ -0,0 +1,191 @@
+// Package client is a Go client for the pongo API.
+//
+// Every method of a Client describes a call to the API without making it.
+// Do makes the call and decodes the result, and Text makes it and returns
+// the text the server renders for people, as pingo shows it:
+//
+//	c := client.New("http://localhost:8080")
+//
+//	player, err := c.CreatePlayer("OnlyRealGs", "2pac").Do(ctx)
+//	var e *client.Error
+//	if errors.As(err, &e) && e.Code == problem.PlayerExists {
+//		// ...
+//	}
+//
+//	status, text, err := c.GetLeaderboard("OnlyRealGs").Text(ctx)
+//
+// Errors responded by the API are returned as an *Error, with the code of
+// what went wrong. The codes are listed in the problem package.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/6ixfigs/pingypongy/pkg/problem"
+)
+
+// Client calls the pongo API at BaseURL, e.g. http://localhost:8080.
+type Client struct {
+	BaseURL string
+
+	// HTTPClient makes the requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+
+	// Code tells what went wrong, e.g. problem.PlayerExists. It is empty
+	// if the response wasn't problem details, such as a failed webhook test.
+	Code      string
+	Message   string
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	if e.Code == problem.InternalError && e.RequestID != "" {
+		return fmt.Sprintf("%s: %s (request %s)", e.Code, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Call is a call to the API, resulting in a T.
+type Call[T any] struct {
+	c      *Client
+	method string
+	path   string
+	query  url.Values
+	form   url.Values
+}
+
+func newCall[T any](c *Client, method, path string, query, form url.Values) *Call[T] {
+	return &Call[T]{c: c, method: method, path: path, query: query, form: form}
+}
+
+// Do makes the call and decodes its result. If the API responds with an
+// error, an *Error is returned along with the result it sent, if any, like
+// the delivery of a failed webhook test.
+func (call *Call[T]) Do(ctx context.Context) (T, error) {
+	var v T
+
+	resp, body, err := call.send(ctx, "application/json")
+	if err != nil {
+		return v, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		err = responseError(resp, body)
+		if mediaType(resp) != "application/json" {
+			return v, err
+		}
+	}
+
+	if jsonErr := json.Unmarshal(body, &v); jsonErr != nil && err == nil {
+		err = fmt.Errorf("decode %s %s: %w", call.method, call.path, jsonErr)
+	}
+	return v, err
+}
+
+// Text makes the call and returns the status and the text of its result.
+// If the API responds with an error, an *Error is returned along with the
+// text it sent, if any, like the report of a failed webhook test.
+func (call *Call[T]) Text(ctx context.Context) (status, text string, err error) {
+	resp, body, err := call.send(ctx, "text/plain, "+problem.ContentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		err = responseError(resp, body)
+		if mediaType(resp) == problem.ContentType {
+			return resp.Status, "", err
+		}
+	}
+	return resp.Status, string(body), err
+}
+
+func (call *Call[T]) send(ctx context.Context, accept string) (*http.Response, []byte, error) {
+	u := call.c.BaseURL + "/api/v1" + call.path
+	if len(call.query) > 0 {
+		u += "?" + call.query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, call.method, u, strings.NewReader(call.form.Encode()))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", accept)
+
+	httpClient := call.c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// responseError turns an error response into an *Error. Responses that
+// aren't problem details only tell their status.
+func responseError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    resp.Status,
+		RequestID:  resp.Header.Get(problem.RequestIDHeader),
+	}
+	if mediaType(resp) != problem.ContentType {
+		return e
+	}
+
+	p := &problem.Problem{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return e
+	}
+
+	e.Code = p.Code
+	e.Message = p.Detail
+	if p.RequestID != "" {
+		e.RequestID = p.RequestID
+	}
+	return e
+}
+
+func mediaType(resp *http.Response) string {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType
+}
+
+// path joins the segments into a path, escaping each of them.
+func path(segments ...any) string {
+	var b strings.Builder
+	for _, s := range segments {
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(fmt.Sprint(s)))
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Challenge challenges a player above the challenger on a ladder.
+func (c *Client) Challenge(leaderboard, challenger, challenged string) *Call[*Challenge] {
+	form := url.Values{"challenger": {challenger}, "challenged": {challenged}}
+	return newCall[*Challenge](c, http.MethodPost, path("leaderboards", leaderboard, "ladder", "challenges"), nil, form)
+}
+
+// ListChallenges lists the open challenges of a ladder.
+func (c *Client) ListChallenges(leaderboard string) *Call[[]*Challenge] {
+	return newCall[[]*Challenge](c, http.MethodGet, path("leaderboards", leaderboard, "ladder", "challenges"), nil, nil)
+}
+
+// AcceptChallenge accepts a challenge.
+func (c *Client) AcceptChallenge(leaderboard string, challengeID int) *Call[*Challenge] {
+	return newCall[*Challenge](c, http.MethodPost, path("leaderboards", leaderboard, "ladder", "challenges", challengeID, "accept"), nil, nil)
+}
+
+// PlayChallenge records the match of an accepted challenge, with the score
+// of the challenger first.
+func (c *Client) PlayChallenge(leaderboard string, challengeID int, score string) *Call[*ChallengeResult] {
+	form := url.Values{"score": {score}}
+	return newCall[*ChallengeResult](c, http.MethodPost, path("leaderboards", leaderboard, "ladder", "challenges", challengeID, "matches"), nil, form)
+}
+
+// LadderHistory lists the changes of positions on a ladder, of a single
+// player if player isn't empty.
+func (c *Client) LadderHistory(leaderboard, player string) *Call[[]*LadderChange] {
+	var query url.Values
+	if player != "" {
+		query = url.Values{"player": {player}}
+	}
+	return newCall[[]*LadderChange](c, http.MethodGet, path("leaderboards", leaderboard, "ladder", "history"), query, nil)
+}
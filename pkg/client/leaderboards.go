@@ -0,0 +1,50 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// LeaderboardOptions are the settings of a new leaderboard. Zero values
+// leave the server's defaults.
+type LeaderboardOptions struct {
+	// Type is elo or ladder.
+	Type string
+
+	// ChallengeRange is how many places above them a player can challenge
+	// on a ladder.
+	ChallengeRange int
+
+	// ExpiryDays is the number of days to play a ladder challenge before it
+	// is forfeited.
+	ExpiryDays int
+}
+
+// CreateLeaderboard creates a leaderboard. opts may be nil.
+func (c *Client) CreateLeaderboard(name string, opts *LeaderboardOptions) *Call[*Leaderboard] {
+	form := url.Values{"name": {name}}
+	if opts != nil {
+		if opts.Type != "" {
+			form.Set("type", opts.Type)
+		}
+		if opts.ChallengeRange != 0 {
+			form.Set("challenge_range", strconv.Itoa(opts.ChallengeRange))
+		}
+		if opts.ExpiryDays != 0 {
+			form.Set("expiry_days", strconv.Itoa(opts.ExpiryDays))
+		}
+	}
+	return newCall[*Leaderboard](c, http.MethodPost, "/leaderboards", nil, form)
+}
+
+// GetLeaderboard gets the standings of a leaderboard.
+func (c *Client) GetLeaderboard(name string) *Call[*Standings] {
+	return newCall[*Standings](c, http.MethodGet, path("leaderboards", name), nil, nil)
+}
+
+// Predict predicts the outcome of a match between two players.
+func (c *Client) Predict(leaderboard, player1, player2 string) *Call[*Prediction] {
+	query := url.Values{"player1": {player1}, "player2": {player2}}
+	return newCall[*Prediction](c, http.MethodGet, path("leaderboards", leaderboard, "predict"), query, nil)
+}
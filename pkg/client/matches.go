@@ -0,0 +1,31 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// RecordMatch records a match between two players, with a score such as
+// "2-1". recordedBy is the player reporting the result, if known, who then
+// can't confirm or dispute it.
+func (c *Client) RecordMatch(leaderboard, player1, player2, score, recordedBy string) *Call[*MatchResult] {
+	form := url.Values{"player1": {player1}, "player2": {player2}, "score": {score}}
+	if recordedBy != "" {
+		form.Set("recorded_by", recordedBy)
+	}
+	return newCall[*MatchResult](c, http.MethodPost, path("leaderboards", leaderboard, "matches"), nil, form)
+}
+
+// ConfirmMatch confirms the result of a match on behalf of one of its
+// players.
+func (c *Client) ConfirmMatch(leaderboard string, matchID int, player string) *Call[*MatchStatus] {
+	form := url.Values{"player": {player}}
+	return newCall[*MatchStatus](c, http.MethodPost, path("leaderboards", leaderboard, "matches", matchID, "confirm"), nil, form)
+}
+
+// DisputeMatch disputes the result of a match on behalf of one of its
+// players.
+func (c *Client) DisputeMatch(leaderboard string, matchID int, player string) *Call[*MatchStatus] {
+	form := url.Values{"player": {player}}
+	return newCall[*MatchStatus](c, http.MethodPost, path("leaderboards", leaderboard, "matches", matchID, "dispute"), nil, form)
+}
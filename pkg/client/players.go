@@ -0,0 +1,39 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// CreatePlayer adds a player to a leaderboard.
+func (c *Client) CreatePlayer(leaderboard, username string) *Call[*Player] {
+	form := url.Values{"username": {username}}
+	return newCall[*Player](c, http.MethodPost, path("leaderboards", leaderboard, "players"), nil, form)
+}
+
+// GetPlayer gets the stats of a player.
+func (c *Client) GetPlayer(leaderboard, username string) *Call[*Player] {
+	return newCall[*Player](c, http.MethodGet, path("leaderboards", leaderboard, "players", username), nil, nil)
+}
+
+// SuggestOpponents suggests who a player should play next.
+func (c *Client) SuggestOpponents(leaderboard, username string) *Call[*Suggestions] {
+	return newCall[*Suggestions](c, http.MethodGet, path("leaderboards", leaderboard, "players", username, "suggest"), nil, nil)
+}
+
+// LinkIdentity links a player to their account on a provider, e.g. slack,
+// discord or email.
+func (c *Client) LinkIdentity(leaderboard, username, provider, id string) *Call[*Identity] {
+	form := url.Values{"provider": {provider}, "id": {id}}
+	return newCall[*Identity](c, http.MethodPost, path("leaderboards", leaderboard, "players", username, "identities"), nil, form)
+}
+
+// ListIdentities lists the accounts a player is linked to.
+func (c *Client) ListIdentities(leaderboard, username string) *Call[[]*Identity] {
+	return newCall[[]*Identity](c, http.MethodGet, path("leaderboards", leaderboard, "players", username, "identities"), nil, nil)
+}
+
+// UnlinkIdentity unlinks a player from their account on a provider.
+func (c *Client) UnlinkIdentity(leaderboard, username, provider string) *Call[*Identity] {
+	return newCall[*Identity](c, http.MethodDelete, path("leaderboards", leaderboard, "players", username, "identities", provider), nil, nil)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TournamentOptions are the settings of a new tournament. Zero values leave
+// the server's defaults.
+type TournamentOptions struct {
+	// Format is single-elimination, round-robin or swiss.
+	Format string
+
+	// Rounds is the number of rounds of a Swiss tournament.
+	Rounds int
+}
+
+// CreateTournament creates a tournament between the players, who are seeded
+// by their current Elo. opts may be nil.
+func (c *Client) CreateTournament(leaderboard, name string, players []string, opts *TournamentOptions) *Call[*TournamentView] {
+	form := url.Values{"name": {name}, "players": {strings.Join(players, ",")}}
+	if opts != nil {
+		if opts.Format != "" {
+			form.Set("format", opts.Format)
+		}
+		if opts.Rounds != 0 {
+			form.Set("rounds", strconv.Itoa(opts.Rounds))
+		}
+	}
+	return newCall[*TournamentView](c, http.MethodPost, path("leaderboards", leaderboard, "tournaments"), nil, form)
+}
+
+// ListTournaments lists the tournaments of a leaderboard.
+func (c *Client) ListTournaments(leaderboard string) *Call[[]*TournamentSummary] {
+	return newCall[[]*TournamentSummary](c, http.MethodGet, path("leaderboards", leaderboard, "tournaments"), nil, nil)
+}
+
+// GetTournament gets a tournament along with its matches.
+func (c *Client) GetTournament(leaderboard, name string) *Call[*TournamentView] {
+	return newCall[*TournamentView](c, http.MethodGet, path("leaderboards", leaderboard, "tournaments", name), nil, nil)
+}
+
+// RecordTournamentMatch records the open tournament match between two
+// players.
+func (c *Client) RecordTournamentMatch(leaderboard, tournament, player1, player2, score string) *Call[*TournamentResult] {
+	form := url.Values{"player1": {player1}, "player2": {player2}, "score": {score}}
+	return newCall[*TournamentResult](c, http.MethodPost, path("leaderboards", leaderboard, "tournaments", tournament, "matches"), nil, form)
+}
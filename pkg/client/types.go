@@ -0,0 +1,36 @@
+package client
+
+import "github.com/6ixfigs/pingypongy/internal/models"
+
+// The resources of the API, as the server describes them.
+type (
+	Leaderboard      = models.Leaderboard
+	Player           = models.Player
+	MatchScore       = models.MatchScore
+	MatchResult      = models.MatchResult
+	EloChange        = models.EloChange
+	Prediction       = models.Prediction
+	Suggestion       = models.Suggestion
+	Identity         = models.Identity
+	Tournament       = models.Tournament
+	TournamentPlayer = models.TournamentPlayer
+	TournamentMatch  = models.TournamentMatch
+	Standing         = models.Standing
+	Challenge        = models.Challenge
+	LadderChange     = models.LadderChange
+	Webhook          = models.Webhook
+	WebhookDelivery  = models.WebhookDelivery
+	MessageTemplate  = models.MessageTemplate
+
+	Standings         = models.Standings
+	Suggestions       = models.Suggestions
+	MatchStatus       = models.MatchStatus
+	TournamentView    = models.TournamentView
+	TournamentSummary = models.TournamentSummary
+	TournamentResult  = models.TournamentResult
+	ChallengeResult   = models.ChallengeResult
+	WebhookSecret     = models.WebhookSecret
+	WebhookDeliveries = models.WebhookDeliveries
+	DeletedWebhooks   = models.DeletedWebhooks
+	TemplatePreview   = models.TemplatePreview
+)
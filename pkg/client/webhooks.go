@@ -0,0 +1,138 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WebhookOptions are the settings of a new webhook. Zero values leave the
+// server's defaults.
+type WebhookOptions struct {
+	// Format is slack, text, discord, teams or json. By default it is
+	// picked from the URL.
+	Format string
+
+	// Events are the events to subscribe to, e.g. match.recorded. By
+	// default the webhook is notified of all events.
+	Events []string
+}
+
+// WebhookUpdate are the changes to a webhook. Nil fields are left as they
+// are, except that a new URL without a format picks the format from the URL.
+type WebhookUpdate struct {
+	URL    *string
+	Format *string
+
+	// Events are the events to subscribe to. An empty, non-nil slice
+	// subscribes to all events again.
+	Events  []string
+	Enabled *bool
+}
+
+// RegisterWebhook registers a webhook to notify of the events of a
+// leaderboard. opts may be nil.
+func (c *Client) RegisterWebhook(leaderboard, webhookURL string, opts *WebhookOptions) *Call[*WebhookSecret] {
+	form := url.Values{"url": {webhookURL}}
+	if opts != nil {
+		if opts.Format != "" {
+			form.Set("format", opts.Format)
+		}
+		if len(opts.Events) > 0 {
+			form.Set("events", strings.Join(opts.Events, ","))
+		}
+	}
+	return newCall[*WebhookSecret](c, http.MethodPost, path("leaderboards", leaderboard, "webhooks"), nil, form)
+}
+
+// ListWebhooks lists the webhooks of a leaderboard.
+func (c *Client) ListWebhooks(leaderboard string) *Call[[]*Webhook] {
+	return newCall[[]*Webhook](c, http.MethodGet, path("leaderboards", leaderboard, "webhooks"), nil, nil)
+}
+
+// UpdateWebhook changes a webhook.
+func (c *Client) UpdateWebhook(leaderboard string, webhookID int, update *WebhookUpdate) *Call[*Webhook] {
+	form := url.Values{}
+	if update.URL != nil {
+		form.Set("url", *update.URL)
+	}
+	if update.Format != nil {
+		form.Set("format", *update.Format)
+	}
+	if update.Events != nil {
+		form.Set("events", strings.Join(update.Events, ","))
+	}
+	if update.Enabled != nil {
+		form.Set("enabled", strconv.FormatBool(*update.Enabled))
+	}
+	return newCall[*Webhook](c, http.MethodPatch, path("leaderboards", leaderboard, "webhooks", webhookID), nil, form)
+}
+
+// DeleteWebhook deletes a webhook.
+func (c *Client) DeleteWebhook(leaderboard string, webhookID int) *Call[*Webhook] {
+	return newCall[*Webhook](c, http.MethodDelete, path("leaderboards", leaderboard, "webhooks", webhookID), nil, nil)
+}
+
+// DeleteWebhooks deletes all webhooks of a leaderboard.
+func (c *Client) DeleteWebhooks(leaderboard string) *Call[*DeletedWebhooks] {
+	return newCall[*DeletedWebhooks](c, http.MethodDelete, path("leaderboards", leaderboard, "webhooks"), nil, nil)
+}
+
+// TestWebhook sends a sample match result to a webhook straight away. If
+// the webhook fails, the delivery is returned along with an *Error.
+func (c *Client) TestWebhook(leaderboard string, webhookID int) *Call[*WebhookDelivery] {
+	return newCall[*WebhookDelivery](c, http.MethodPost, path("leaderboards", leaderboard, "webhooks", webhookID, "test"), nil, nil)
+}
+
+// WebhookDeliveries gets the latest delivery attempts of a webhook.
+func (c *Client) WebhookDeliveries(leaderboard string, webhookID int) *Call[*WebhookDeliveries] {
+	return newCall[*WebhookDeliveries](c, http.MethodGet, path("leaderboards", leaderboard, "webhooks", webhookID, "deliveries"), nil, nil)
+}
+
+// EnableWebhook enables a webhook again, after it was disabled for failing.
+func (c *Client) EnableWebhook(leaderboard string, webhookID int) *Call[*Webhook] {
+	return newCall[*Webhook](c, http.MethodPost, path("leaderboards", leaderboard, "webhooks", webhookID, "enable"), nil, nil)
+}
+
+// RotateWebhookSecret replaces the signing secret of a webhook.
+func (c *Client) RotateWebhookSecret(leaderboard string, webhookID int) *Call[*WebhookSecret] {
+	return newCall[*WebhookSecret](c, http.MethodPost, path("leaderboards", leaderboard, "webhooks", webhookID, "rotate-secret"), nil, nil)
+}
+
+// ListTemplates lists the message templates set on a leaderboard and on its
+// webhooks.
+func (c *Client) ListTemplates(leaderboard string) *Call[[]*MessageTemplate] {
+	return newCall[[]*MessageTemplate](c, http.MethodGet, path("leaderboards", leaderboard, "webhooks", "templates"), nil, nil)
+}
+
+// SetTemplate sets the template for an event on a leaderboard.
+func (c *Client) SetTemplate(leaderboard, event, template string) *Call[*MessageTemplate] {
+	form := url.Values{"template": {template}}
+	return newCall[*MessageTemplate](c, http.MethodPut, path("leaderboards", leaderboard, "webhooks", "templates", event), nil, form)
+}
+
+// SetWebhookTemplate sets the template for an event on a single webhook,
+// taking precedence over the leaderboard's.
+func (c *Client) SetWebhookTemplate(leaderboard string, webhookID int, event, template string) *Call[*MessageTemplate] {
+	form := url.Values{"template": {template}}
+	return newCall[*MessageTemplate](c, http.MethodPut, path("leaderboards", leaderboard, "webhooks", webhookID, "templates", event), nil, form)
+}
+
+// DeleteTemplate deletes the template for an event from a leaderboard.
+func (c *Client) DeleteTemplate(leaderboard, event string) *Call[*MessageTemplate] {
+	return newCall[*MessageTemplate](c, http.MethodDelete, path("leaderboards", leaderboard, "webhooks", "templates", event), nil, nil)
+}
+
+// DeleteWebhookTemplate deletes the template for an event from a single
+// webhook.
+func (c *Client) DeleteWebhookTemplate(leaderboard string, webhookID int, event string) *Call[*MessageTemplate] {
+	return newCall[*MessageTemplate](c, http.MethodDelete, path("leaderboards", leaderboard, "webhooks", webhookID, "templates", event), nil, nil)
+}
+
+// PreviewTemplate renders a template against sample data for an event,
+// without saving it.
+func (c *Client) PreviewTemplate(leaderboard, event, template string) *Call[*TemplatePreview] {
+	form := url.Values{"event": {event}, "template": {template}}
+	return newCall[*TemplatePreview](c, http.MethodPost, path("leaderboards", leaderboard, "webhooks", "templates", "preview"), nil, form)
+}
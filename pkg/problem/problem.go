@@ -0,0 +1,160 @@
+// Package problem describes the errors the pongo API responds with.
+//
+// Clients that accept application/json or application/problem+json get
+// errors as RFC 7807 problem details, with a code telling what went wrong:
+//
+//	HTTP/1.1 409 Conflict
+//	Content-Type: application/problem+json
+//
+//	{
+//	  "type": "about:blank",
+//	  "title": "Conflict",
+//	  "status": 409,
+//	  "detail": "Player 2pac already exists on OnlyRealGs leaderboard.",
+//	  "code": "player_exists",
+//	  "instance": "/api/v1/leaderboards/OnlyRealGs/players",
+//	  "request_id": "pongo/Xh3kLm0q2b-000042"
+//	}
+//
+// Other clients get the detail as plain text. Either way, the request id is
+// sent in the X-Request-Id header, and shows up in the server's logs.
+package problem
+
+import "net/http"
+
+const (
+	ContentType     = "application/problem+json"
+	RequestIDHeader = "X-Request-Id"
+)
+
+// The codes of the errors, by the status they are sent with.
+const (
+	// 400 Bad Request
+	InvalidRequest       = "invalid_request"
+	InvalidParameter     = "invalid_parameter"
+	InvalidScore         = "invalid_score"
+	InvalidURL           = "invalid_url"
+	InvalidEvent         = "invalid_event"
+	InvalidTemplate      = "invalid_template"
+	SamePlayer           = "same_player"
+	DuplicatePlayer      = "duplicate_player"
+	NotEnoughPlayers     = "not_enough_players"
+	NotALadder           = "not_a_ladder"
+	OutOfChallengeRange  = "out_of_challenge_range"
+	TournamentFinished   = "tournament_finished"
+	NoOpenMatch          = "no_open_match"
+	DrawNotAllowed       = "draw_not_allowed"
+	UnsupportedMediaType = "unsupported_media_type"
+
+	// 403 Forbidden
+	NotMatchPlayer = "not_match_player"
+
+	// 404 Not Found
+	NotFound            = "not_found"
+	LeaderboardNotFound = "leaderboard_not_found"
+	PlayerNotFound      = "player_not_found"
+	MatchNotFound       = "match_not_found"
+	TournamentNotFound  = "tournament_not_found"
+	ChallengeNotFound   = "challenge_not_found"
+	WebhookNotFound     = "webhook_not_found"
+	TemplateNotFound    = "template_not_found"
+	IdentityNotFound    = "identity_not_found"
+
+	// 405 Method Not Allowed
+	MethodNotAllowed = "method_not_allowed"
+
+	// 409 Conflict
+	LeaderboardExists    = "leaderboard_exists"
+	PlayerExists         = "player_exists"
+	TournamentExists     = "tournament_exists"
+	WebhookExists        = "webhook_exists"
+	IdentityTaken        = "identity_taken"
+	ChallengeOpen        = "challenge_open"
+	ChallengeNotPending  = "challenge_not_pending"
+	ChallengeNotAccepted = "challenge_not_accepted"
+	MatchAlreadyReviewed = "match_already_reviewed"
+
+	// 500 Internal Server Error
+	InternalError = "internal_error"
+)
+
+var statuses = map[string]int{
+	InvalidRequest:       http.StatusBadRequest,
+	InvalidParameter:     http.StatusBadRequest,
+	InvalidScore:         http.StatusBadRequest,
+	InvalidURL:           http.StatusBadRequest,
+	InvalidEvent:         http.StatusBadRequest,
+	InvalidTemplate:      http.StatusBadRequest,
+	SamePlayer:           http.StatusBadRequest,
+	DuplicatePlayer:      http.StatusBadRequest,
+	NotEnoughPlayers:     http.StatusBadRequest,
+	NotALadder:           http.StatusBadRequest,
+	OutOfChallengeRange:  http.StatusBadRequest,
+	TournamentFinished:   http.StatusBadRequest,
+	NoOpenMatch:          http.StatusBadRequest,
+	DrawNotAllowed:       http.StatusBadRequest,
+	UnsupportedMediaType: http.StatusUnsupportedMediaType,
+
+	NotMatchPlayer: http.StatusForbidden,
+
+	NotFound:            http.StatusNotFound,
+	LeaderboardNotFound: http.StatusNotFound,
+	PlayerNotFound:      http.StatusNotFound,
+	MatchNotFound:       http.StatusNotFound,
+	TournamentNotFound:  http.StatusNotFound,
+	ChallengeNotFound:   http.StatusNotFound,
+	WebhookNotFound:     http.StatusNotFound,
+	TemplateNotFound:    http.StatusNotFound,
+	IdentityNotFound:    http.StatusNotFound,
+
+	MethodNotAllowed: http.StatusMethodNotAllowed,
+
+	LeaderboardExists:    http.StatusConflict,
+	PlayerExists:         http.StatusConflict,
+	TournamentExists:     http.StatusConflict,
+	WebhookExists:        http.StatusConflict,
+	IdentityTaken:        http.StatusConflict,
+	ChallengeOpen:        http.StatusConflict,
+	ChallengeNotPending:  http.StatusConflict,
+	ChallengeNotAccepted: http.StatusConflict,
+	MatchAlreadyReviewed: http.StatusConflict,
+
+	InternalError: http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status an error with the code is sent with.
+// Unknown codes are internal errors.
+func Status(code string) int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem is an error response, in the form of RFC 7807 problem details
+// extended with the error's code and the id of the request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Code      string `json:"code"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// New returns the problem with the code and detail.
+func New(code, detail string) *Problem {
+	status := Status(code)
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import "time"
+
+// SchemaVersion is the version of the event schema. It is bumped whenever a
+// change could break receivers, such as removing or renaming a field. Adding
+// fields or event types doesn't bump it, so receivers should ignore what they
+// don't know.
+const SchemaVersion = 1
+
+// The types of events.
+const (
+	LeaderboardViewed       = "leaderboard.viewed"
+	PlayerCreated           = "player.created"
+	StatsViewed             = "stats.viewed"
+	MatchRecorded           = "match.recorded"
+	TournamentMatchRecorded = "tournament.match_recorded"
+	ChallengeIssued         = "challenge.issued"
+	ChallengeAccepted       = "challenge.accepted"
+	ChallengePlayed         = "challenge.played"
+	ChallengeForfeited      = "challenge.forfeited"
+	PairingsSuggested       = "pairings.suggested"
+
+	// Test is sent when a webhook is tested. It carries a sample match and
+	// isn't part of Events, as webhooks can't subscribe to it.
+	Test = "webhook.test"
+)
+
+// Events lists every type of event, in the order they are documented.
+var Events = []string{
+	LeaderboardViewed,
+	PlayerCreated,
+	StatsViewed,
+	MatchRecorded,
+	TournamentMatchRecorded,
+	ChallengeIssued,
+	ChallengeAccepted,
+	ChallengePlayed,
+	ChallengeForfeited,
+	PairingsSuggested,
+}
+
+// Event is the body of a notification sent to a webhook in the json format.
+// Which of the optional fields are set depends on the type of the event.
+type Event struct {
+	Version     int       `json:"version"`
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Leaderboard string    `json:"leaderboard"`
+	Timestamp   time.Time `json:"timestamp"`
+
+	// Text is the event as the plain text message sent to chat webhooks.
+	Text string `json:"text"`
+
+	Match      *Match      `json:"match,omitempty"`
+	Players    []*Player   `json:"players,omitempty"`
+	Challenge  *Challenge  `json:"challenge,omitempty"`
+	Tournament *Tournament `json:"tournament,omitempty"`
+	Pairings   []*Pairing  `json:"pairings,omitempty"`
+}
+
+// Player is a player's standing on the leaderboard. Rank is the player's
+// place in the leaderboard.viewed event and zero otherwise.
+type Player struct {
+	Username       string `json:"username"`
+	Rank           int    `json:"rank,omitempty"`
+	MatchesWon     int    `json:"matches_won"`
+	MatchesDrawn   int    `json:"matches_drawn"`
+	MatchesLost    int    `json:"matches_lost"`
+	GamesWon       int    `json:"games_won"`
+	GamesLost      int    `json:"games_lost"`
+	CurrentStreak  int    `json:"current_streak"`
+	Elo            int    `json:"elo"`
+	LadderPosition int    `json:"ladder_position,omitempty"`
+}
+
+// Match is a recorded match. Winner is empty for a draw.
+type Match struct {
+	ID      int          `json:"id"`
+	Score   string       `json:"score"`
+	Winner  string       `json:"winner,omitempty"`
+	Player1 *MatchPlayer `json:"player1"`
+	Player2 *MatchPlayer `json:"player2"`
+}
+
+// MatchPlayer is a player's side of a match.
+type MatchPlayer struct {
+	Username      string `json:"username"`
+	Score         int    `json:"score"`
+	EloBefore     int    `json:"elo_before"`
+	EloAfter      int    `json:"elo_after"`
+	CurrentStreak int    `json:"current_streak"`
+}
+
+// Challenge is a ladder challenge. The positions are the players' positions
+// on the ladder once the event has happened.
+type Challenge struct {
+	ID                 int    `json:"id"`
+	Status             string `json:"status"`
+	Challenger         string `json:"challenger"`
+	ChallengerPosition int    `json:"challenger_position"`
+	Challenged         string `json:"challenged"`
+	ChallengedPosition int    `json:"challenged_position"`
+	ExpiresAt          string `json:"expires_at,omitempty"`
+	MatchID            int    `json:"match_id,omitempty"`
+}
+
+// Tournament is the tournament a match was played in. Winner is set once the
+// tournament is finished.
+type Tournament struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Winner string `json:"winner,omitempty"`
+}
+
+// Pairing is a suggested match.
+type Pairing struct {
+	Player1        string  `json:"player1"`
+	Player2        string  `json:"player2"`
+	WinProbability float64 `json:"win_probability"`
+}
@@ -0,0 +1,123 @@
+// Package webhook verifies the notifications pongo sends to webhooks.
+//
+// Every notification is signed with the secret the webhook was given when it
+// was registered. The signature is an HMAC-SHA256 of the timestamp and the
+// body joined by a dot, sent along with the timestamp so that receivers can
+// reject old notifications being replayed:
+//
+//	X-Pongo-Timestamp: 1735689600
+//	X-Pongo-Signature: v1=<hex encoded HMAC-SHA256 of "1735689600.<body>">
+//
+// A receiver checks a notification with VerifyRequest:
+//
+//	body, err := webhook.VerifyRequest(r, secret, 5*time.Minute)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusUnauthorized)
+//		return
+//	}
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimestampHeader = "X-Pongo-Timestamp"
+	SignatureHeader = "X-Pongo-Signature"
+)
+
+// signatureVersion prefixes the signatures in SignatureHeader. It changes if
+// the signing scheme ever does, so that receivers can tell them apart.
+const signatureVersion = "v1"
+
+// maxBodySize caps how much of a request VerifyRequest reads.
+const maxBodySize = 1 << 20
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrInvalidTimestamp = errors.New("webhook: invalid timestamp")
+	ErrExpiredTimestamp = errors.New("webhook: timestamp outside of tolerance")
+)
+
+// Sign returns the value of SignatureHeader for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + "=" + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// Verify checks that signature, the value of SignatureHeader, was made with
+// secret for body sent at timestamp, the value of TimestampHeader. Timestamps
+// further than tolerance from now are rejected; a tolerance of zero disables
+// the check. The header may hold several comma separated signatures, any of
+// which can match.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(seconds, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrExpiredTimestamp
+		}
+	}
+
+	expected := mac(secret, seconds, body)
+	for _, s := range strings.Split(signature, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(s), "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+
+		decoded, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// VerifyRequest reads the body of r and verifies its signature. The body is
+// returned, and r.Body is replaced so that it can be read again.
+func VerifyRequest(r *http.Request, secret string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = Verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func mac(secret string, timestamp int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_0123456789abcdef"
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"match.recorded","text":"2pac 2 - 1 eazy-e"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign(testSecret, now, body)
+
+	if !strings.HasPrefix(signature, "v1=") {
+		t.Errorf("Sign() = %s, want a v1= signature", signature)
+	}
+	if err := Verify(testSecret, ts, signature, body, 5*time.Minute); err != nil {
+		t.Fatalf("Verify() = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      string
+		tolerance time.Duration
+		want      error
+	}{
+		{
+			name:      "rotated secret",
+			secret:    testSecret,
+			timestamp: ts,
+			signature: Sign("whsec_old", now, body) + ", " + signature,
+			body:      string(body),
+			tolerance: 5 * time.Minute,
+		},
+		{
+			name:      "wrong secret",
+			secret:    "whsec_not_the_secret",
+			timestamp: ts,
+			signature: signature,
+			body:      string(body),
+			tolerance: 5 * time.Minute,
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "modified body",
+			secret:    testSecret,
+			timestamp: ts,
+			signature: signature,
+			body:      `{"type":"match.recorded","text":"2pac 0 - 2 eazy-e"}`,
+			tolerance: 5 * time.Minute,
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "modified timestamp",
+			secret:    testSecret,
+			timestamp: strconv.FormatInt(now.Unix()+1, 10),
+			signature: signature,
+			body:      string(body),
+			tolerance: 5 * time.Minute,
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "expired timestamp",
+			secret:    testSecret,
+			timestamp: strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+			signature: Sign(testSecret, now.Add(-time.Hour), body),
+			body:      string(body),
+			tolerance: 5 * time.Minute,
+			want:      ErrExpiredTimestamp,
+		},
+		{
+			name:      "no tolerance",
+			secret:    testSecret,
+			timestamp: strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+			signature: Sign(testSecret, now.Add(-time.Hour), body),
+			body:      string(body),
+		},
+		{
+			name:      "malformed timestamp",
+			secret:    testSecret,
+			timestamp: "yesterday",
+			signature: signature,
+			body:      string(body),
+			want:      ErrInvalidTimestamp,
+		},
+		{
+			name:      "missing signature",
+			secret:    testSecret,
+			timestamp: ts,
+			body:      string(body),
+			want:      ErrMissingSignature,
+		},
+		{
+			name:      "unknown version",
+			secret:    testSecret,
+			timestamp: ts,
+			signature: "v0=" + strings.TrimPrefix(signature, "v1="),
+			body:      string(body),
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "not hex",
+			secret:    testSecret,
+			timestamp: ts,
+			signature: "v1=not-hex",
+			body:      string(body),
+			want:      ErrInvalidSignature,
+		},
+		{
+			name:      "no version",
+			secret:    testSecret,
+			timestamp: ts,
+			signature: strings.TrimPrefix(signature, "v1="),
+			body:      string(body),
+			want:      ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, []byte(tt.body), tt.tolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := `{"type":"player.created"}`
+	now := time.Now()
+
+	r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	r.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	r.Header.Set(SignatureHeader, Sign(testSecret, now, []byte(body)))
+
+	got, err := VerifyRequest(r, testSecret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("VerifyRequest() = %v, want nil", err)
+	}
+	if string(got) != body {
+		t.Errorf("VerifyRequest() body = %s, want %s", got, body)
+	}
+
+	// The body can still be read by the receiver.
+	again, err := io.ReadAll(r.Body)
+	if err != nil || string(again) != body {
+		t.Errorf("r.Body = %q, %v, want %s", again, err, body)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+	r.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	r.Header.Set(SignatureHeader, Sign("whsec_not_the_secret", now, []byte(body)))
+
+	if _, err := VerifyRequest(r, testSecret, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyRequest() = %v, want %v", err, ErrInvalidSignature)
+	}
+}